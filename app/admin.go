@@ -0,0 +1,181 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/wangshanqi84-gif/sagittarius/app/config"
+	"github.com/wangshanqi84-gif/sagittarius/cores/env"
+	"github.com/wangshanqi84-gif/sagittarius/cores/health"
+	"github.com/wangshanqi84-gif/sagittarius/cores/metric/pprof"
+	"github.com/wangshanqi84-gif/sagittarius/cores/server"
+	"github.com/wangshanqi84-gif/sagittarius/logger"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	redacted = "******"
+)
+
+var (
+	// secretKeys 配置中需要隐藏的字段, 小写匹配
+	secretKeys = []string{"password", "secret", "token", "accesskey"}
+	// dsnKeys 值为dsn的字段, 隐藏其中的密码
+	dsnKeys = map[string]bool{"master": true, "slaves": true}
+	// dsnPassword user:password@ 及 password=xxx
+	dsnPassword = regexp.MustCompile(`([^:/@\s]+):([^@\s]*)@`)
+	dsnKV       = regexp.MustCompile(`(?i)(password=)\S+`)
+)
+
+// initAdmin 创建管理端口服务, 未配置端口时返回nil
+func initAdmin(ctx context.Context, cfg *config.AdminConfig) (*http.Server, *http.ServeMux) {
+	if cfg == nil || cfg.Port <= 0 {
+		return nil, nil
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/livez", r.health.Handler(health.Liveness))
+	mux.Handle("/healthz", r.health.Handler(health.Dependency))
+	mux.Handle("/readyz", r.health.Handler(health.Readiness))
+	mux.HandleFunc("/info", adminInfo)
+	mux.HandleFunc("/config", adminConfig)
+	mux.HandleFunc("/routes", adminRoutes)
+	// pprof需显式开启, 避免管理端口暴露时泄露运行信息
+	if cfg.PProfEnable || strings.ToLower(env.GetEnv(env.SgtPProfEnable)) == "true" {
+		pprof.Register(mux)
+		if cfg.BlockProfileRate > 0 {
+			runtime.SetBlockProfileRate(cfg.BlockProfileRate)
+		}
+		if cfg.MutexProfileFraction > 0 {
+			runtime.SetMutexProfileFraction(cfg.MutexProfileFraction)
+		}
+	}
+	logger.Gen(ctx, "admin server init, port:%d", cfg.Port)
+	return &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.Port),
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}, mux
+}
+
+// AdminEnabled 是否配置了管理端口
+func (r *router) AdminEnabled() bool {
+	return r.admin != nil
+}
+
+// AdminHandle 在管理端口注册自定义handler, 未配置管理端口时忽略
+func (r *router) AdminHandle(pattern string, handler http.Handler) {
+	if r.adminMux == nil {
+		return
+	}
+	r.adminMux.Handle(pattern, handler)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+// adminInfo 服务, 构建及运行时信息
+func adminInfo(w http.ResponseWriter, _ *http.Request) {
+	build := map[string]interface{}{}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		build["goVersion"] = bi.GoVersion
+		build["path"] = bi.Main.Path
+		build["version"] = bi.Main.Version
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				build["revision"] = s.Value
+			case "vcs.time":
+				build["time"] = s.Value
+			case "vcs.modified":
+				build["modified"] = s.Value == "true"
+			}
+		}
+	}
+	writeJSON(w, map[string]interface{}{
+		"service": r.info,
+		"env":     env.GetRunEnv(),
+		"build":   build,
+		"runtime": map[string]interface{}{
+			"startAt":    r.startAt.Format(time.RFC3339),
+			"uptime":     time.Since(r.startAt).Truncate(time.Second).String(),
+			"goroutines": runtime.NumGoroutine(),
+			"gomaxprocs": runtime.GOMAXPROCS(0),
+			"numCPU":     runtime.NumCPU(),
+		},
+	})
+}
+
+// adminConfig 当前服务配置, 隐藏密码等敏感字段
+func adminConfig(w http.ResponseWriter, _ *http.Request) {
+	cfg, err := r.Config()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	bs, err := json.Marshal(cfg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var v interface{}
+	if err = json.Unmarshal(bs, &v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, redact("", v))
+}
+
+// adminRoutes 各服务已注册的路由, key为服务名称
+func adminRoutes(w http.ResponseWriter, _ *http.Request) {
+	routes := make(map[string][]server.Route)
+	for idx, srv := range r.srvs {
+		if l, ok := srv.(server.RouteLister); ok {
+			routes[r.names[idx]] = l.RouteList()
+		}
+	}
+	writeJSON(w, routes)
+}
+
+func redact(key string, v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = redact(k, item)
+		}
+		return val
+	case []interface{}:
+		for idx, item := range val {
+			val[idx] = redact(key, item)
+		}
+		return val
+	case string:
+		if val == "" {
+			return val
+		}
+		lower := strings.ToLower(key)
+		for _, s := range secretKeys {
+			if strings.Contains(lower, s) {
+				return redacted
+			}
+		}
+		if dsnKeys[lower] {
+			val = dsnPassword.ReplaceAllString(val, "$1:"+redacted+"@")
+			return dsnKV.ReplaceAllString(val, "${1}"+redacted)
+		}
+	}
+	return v
+}
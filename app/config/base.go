@@ -51,6 +51,12 @@ type ServerConfig struct {
 	Proto string `yaml:"proto" json:"proto" xml:"proto"`
 	// 启动端口
 	Port int `yaml:"port" json:"port" xml:"port"`
+	// OpenAPI文档路径, 仅http有效, 为空不开启
+	OpenAPI string `yaml:"openapi" json:"openapi" xml:"openapi"`
+	// Swagger UI路径, 需同时配置OpenAPI
+	SwaggerUI string `yaml:"swaggerUI" json:"swaggerUI" xml:"swaggerUI"`
+	// 可信代理ip或CIDR, 仅http有效, 只有来自可信代理的请求才使用X-Forwarded-For/X-Real-IP
+	TrustedProxies []string `yaml:"trustedProxies" json:"trustedProxies" xml:"trustedProxies"`
 }
 
 // DiscoveryConfig 服务发现配置
@@ -77,6 +83,8 @@ type DatabaseConfig struct {
 	MaxLifeTime string `yaml:"maxLifeTime" json:"maxLifeTime" xml:"maxLifeTime"`
 	// 链接池链接最大空闲时长
 	MaxIdleTime string `yaml:"maxIdleTime" json:"maxIdleTime" xml:"maxIdleTime"`
+	// 慢查询日志阈值 默认500ms, 负数不记录
+	SlowThreshold string `yaml:"slowThreshold" json:"slowThreshold" xml:"slowThreshold"`
 }
 
 // RedisConfig redis缓存配置
@@ -105,6 +113,8 @@ type RedisConfig struct {
 	Username string `yaml:"username" json:"username" xml:"username"`
 	// 密码
 	Password string `yaml:"password" json:"password" xml:"password"`
+	// 慢命令日志阈值 默认100ms, 负数不记录
+	SlowThreshold string `yaml:"slowThreshold" json:"slowThreshold" xml:"slowThreshold"`
 }
 
 // ClientConfig 下游客户端配置
@@ -123,6 +133,11 @@ type ClientConfig struct {
 	UnUseDiscovery bool `yaml:"unUseDiscovery" json:"unUseDiscovery" xml:"unUseDiscovery"`
 	// 重试次数
 	Retry int `yaml:"retry" json:"retry" xml:"retry"`
+	// http重试策略, 不配置时使用默认策略
+	RetryPolicy *RetryPolicyConfig `yaml:"retryPolicy" json:"retryPolicy" xml:"retryPolicy"`
+	// http负载均衡策略 random/round_robin/weighted_round_robin/least_request/consistent_hash 默认random
+	// weighted_round_robin权重取自服务注册metadata的weight
+	Balancer string `yaml:"balancer" json:"balancer" xml:"balancer"`
 	// 超时时间
 	Timeout string `yaml:"timeout" json:"timeout" xml:"timeout"`
 	// 代理超时时间
@@ -150,6 +165,112 @@ type ClientConfig struct {
 		// ca证书
 		CAFile string `yaml:"caFile" json:"caFile" xml:"caFile"`
 	} `yaml:"tls" json:"tls" xml:"tls"`
+	// http被动健康检查, 不配置不开启
+	Outlier *OutlierConfig `yaml:"outlier" json:"outlier" xml:"outlier"`
+	// 熔断, 不配置不开启
+	Breaker *BreakerConfig `yaml:"breaker" json:"breaker" xml:"breaker"`
+	// 对冲请求, 不配置不开启
+	Hedge *HedgeConfig `yaml:"hedge" json:"hedge" xml:"hedge"`
+}
+
+// OutlierConfig http客户端被动健康检查配置, 不配置的项使用默认值
+type OutlierConfig struct {
+	// 连续失败次数达到后摘除节点 默认5
+	ConsecutiveFailures int `yaml:"consecutiveFailures" json:"consecutiveFailures" xml:"consecutiveFailures"`
+	// 统计周期内错误率达到后摘除节点 默认0.5
+	ErrorRate float64 `yaml:"errorRate" json:"errorRate" xml:"errorRate"`
+	// 统计周期内请求数达到后才按错误率判断 默认10
+	MinRequests int `yaml:"minRequests" json:"minRequests" xml:"minRequests"`
+	// 错误率统计周期 默认10s
+	Interval string `yaml:"interval" json:"interval" xml:"interval"`
+	// 首次摘除时长, 之后每次摘除翻倍 默认30s
+	BaseEjectionTime string `yaml:"baseEjectionTime" json:"baseEjectionTime" xml:"baseEjectionTime"`
+	// 最长摘除时长 默认300s
+	MaxEjectionTime string `yaml:"maxEjectionTime" json:"maxEjectionTime" xml:"maxEjectionTime"`
+	// 最多摘除节点比例(百分比) 默认50
+	MaxEjectionPercent int `yaml:"maxEjectionPercent" json:"maxEjectionPercent" xml:"maxEjectionPercent"`
+}
+
+// RetryPolicyConfig http重试策略, 不配置的项使用默认值
+type RetryPolicyConfig struct {
+	// 首次重试退避时间 默认50ms
+	Backoff string `yaml:"backoff" json:"backoff" xml:"backoff"`
+	// 最大退避时间 默认1s
+	MaxBackoff string `yaml:"maxBackoff" json:"maxBackoff" xml:"maxBackoff"`
+	// 退避倍数 默认2
+	Multiplier float64 `yaml:"multiplier" json:"multiplier" xml:"multiplier"`
+	// 退避抖动比例 0~1 默认0.2
+	Jitter float64 `yaml:"jitter" json:"jitter" xml:"jitter"`
+	// 可重试的应答状态码 默认429/502/503/504
+	StatusCodes []int `yaml:"statusCodes" json:"statusCodes" xml:"statusCodes"`
+	// 可重试的请求方法 默认GET/HEAD/OPTIONS/PUT/DELETE/TRACE
+	Methods []string `yaml:"methods" json:"methods" xml:"methods"`
+	// 包含首次请求在内的最长总耗时, 不配置只受ctx deadline限制
+	MaxElapsed string `yaml:"maxElapsed" json:"maxElapsed" xml:"maxElapsed"`
+	// 10s窗口内重试数不超过 budgetMinRetries + budgetRatio*请求数
+	// 默认0.2, 负数不限制
+	BudgetRatio float64 `yaml:"budgetRatio" json:"budgetRatio" xml:"budgetRatio"`
+	// 默认10
+	BudgetMinRetries int `yaml:"budgetMinRetries" json:"budgetMinRetries" xml:"budgetMinRetries"`
+}
+
+// BreakerConfig 熔断配置, 不配置的项使用默认值
+type BreakerConfig struct {
+	// 熔断维度 service/method 默认service, http下游method按Req.Route熔断, 未设置Route时按service
+	Scope string `yaml:"scope" json:"scope" xml:"scope"`
+	// 统计窗口 默认10s
+	Window string `yaml:"window" json:"window" xml:"window"`
+	// 窗口分桶数 默认10
+	Buckets int `yaml:"buckets" json:"buckets" xml:"buckets"`
+	// 窗口内请求数达到后才判断 默认20
+	MinRequests int `yaml:"minRequests" json:"minRequests" xml:"minRequests"`
+	// 错误率达到后熔断 默认0.5
+	ErrorRate float64 `yaml:"errorRate" json:"errorRate" xml:"errorRate"`
+	// 慢调用耗时, 不配置不统计慢调用
+	SlowCall string `yaml:"slowCall" json:"slowCall" xml:"slowCall"`
+	// 慢调用比例达到后熔断 默认0.5
+	SlowCallRate float64 `yaml:"slowCallRate" json:"slowCallRate" xml:"slowCallRate"`
+	// 熔断持续时间, 之后进入半开 默认5s
+	OpenTimeout string `yaml:"openTimeout" json:"openTimeout" xml:"openTimeout"`
+	// 半开状态探测请求数 默认5
+	HalfOpenRequests int `yaml:"halfOpenRequests" json:"halfOpenRequests" xml:"halfOpenRequests"`
+}
+
+// HedgeConfig 对冲请求配置, 不配置的项使用默认值
+type HedgeConfig struct {
+	// 原请求经过该时间未返回时发出对冲请求 默认100ms
+	Delay string `yaml:"delay" json:"delay" xml:"delay"`
+	// 使用观测到的p95耗时作为延迟, 样本不足时使用delay
+	UseP95 bool `yaml:"useP95" json:"useP95" xml:"useP95"`
+	// 最多对冲请求数 默认1
+	MaxHedges int `yaml:"maxHedges" json:"maxHedges" xml:"maxHedges"`
+	// 允许对冲的安全方法, http默认GET/HEAD/OPTIONS, rpc为完整方法名, 不配置不对冲
+	Methods []string `yaml:"methods" json:"methods" xml:"methods"`
+	// 10s窗口内对冲数不超过 budgetMinHedges + budgetRatio*请求数
+	// 默认0.1, 负数不限制
+	BudgetRatio float64 `yaml:"budgetRatio" json:"budgetRatio" xml:"budgetRatio"`
+	// 默认0
+	BudgetMinHedges int `yaml:"budgetMinHedges" json:"budgetMinHedges" xml:"budgetMinHedges"`
+}
+
+// RateLimitConfig 限流配置
+type RateLimitConfig struct {
+	// 名称
+	Name string `yaml:"name" json:"name" xml:"name"`
+	// 类型 local/redis 默认local
+	Type string `yaml:"type" json:"type" xml:"type"`
+	// 限流维度 ip/route/service/global 默认ip
+	Key string `yaml:"key" json:"key" xml:"key"`
+	// local: 每秒补充令牌数; redis: 窗口内最大请求数
+	Limit int `yaml:"limit" json:"limit" xml:"limit"`
+	// local: 桶容量, 默认等于limit
+	Burst int `yaml:"burst" json:"burst" xml:"burst"`
+	// redis: 窗口大小 默认1s
+	Window string `yaml:"window" json:"window" xml:"window"`
+	// redis: 使用的redis配置名称
+	Redis string `yaml:"redis" json:"redis" xml:"redis"`
+	// redis: key前缀 默认ratelimit:{name}
+	Prefix string `yaml:"prefix" json:"prefix" xml:"prefix"`
 }
 
 type ProducerTopic struct {
@@ -175,6 +296,8 @@ type RocketProducerConfig struct {
 	SecurityToken string `yaml:"securityToken" json:"securityToken" xml:"securityToken"`
 	// 写入最大重试次数
 	MaxRetry int `yaml:"maxRetry" json:"maxRetry" xml:"maxRetry"`
+	// 慢发送日志阈值 默认1s, 负数不记录
+	SlowThreshold string `yaml:"slowThreshold" json:"slowThreshold" xml:"slowThreshold"`
 }
 
 // RocketConsumerConfig rocket consumer配置
@@ -203,6 +326,8 @@ type RocketConsumerConfig struct {
 	MaxReconsumeTimes int32 `yaml:"maxReconsumeTimes" json:"maxReconsumeTimes" xml:"maxReconsumeTimes"`
 	// Tag标签过滤器
 	Expression string `yaml:"expression" json:"expression" xml:"expression"`
+	// 慢消费日志阈值 默认1s, 负数不记录
+	SlowThreshold string `yaml:"slowThreshold" json:"slowThreshold" xml:"slowThreshold"`
 }
 
 // KafkaProducerConfig kafka producer配置
@@ -223,6 +348,8 @@ type KafkaProducerConfig struct {
 	MaxRetry int `yaml:"maxRetry" json:"maxRetry" toml:"maxRetry"`
 	// 模式 sync/async 默认async
 	Mode string `yaml:"mode" json:"mode" toml:"mode"`
+	// 慢发送日志阈值 默认1s, 负数不记录
+	SlowThreshold string `yaml:"slowThreshold" json:"slowThreshold" toml:"slowThreshold"`
 }
 
 // KafkaConsumerConfig kafka consumer配置
@@ -251,6 +378,32 @@ type KafkaConsumerConfig struct {
 	WorkerNumbers int `yaml:"workerNumbers" json:"workerNumbers" toml:"workerNumbers"`
 	// handler队列缓冲区大小
 	SeqNumbers int `yaml:"seqNumbers" json:"seqNumbers" toml:"seqNumbers"`
+	// 慢处理日志阈值 默认1s, 负数不记录
+	SlowThreshold string `yaml:"slowThreshold" json:"slowThreshold" toml:"slowThreshold"`
+}
+
+// ShutdownConfig 优雅关闭配置
+type ShutdownConfig struct {
+	// 排空时间, 期间服务未就绪但继续处理请求, 等待服务发现摘除生效 默认开启服务发现时5s, 否则0
+	Grace string `yaml:"grace" json:"grace" xml:"grace"`
+	// 等待处理中请求结束的最长时间 默认15s
+	Timeout string `yaml:"timeout" json:"timeout" xml:"timeout"`
+}
+
+// AdminConfig 管理端口配置, 提供metrics/pprof/健康检查/服务信息/路由等内部接口, 不注册到服务发现
+type AdminConfig struct {
+	// 端口 为0不开启
+	Port int `yaml:"port" json:"port" xml:"port"`
+	// 就绪检查间隔, 结果同步到grpc健康检查 默认5s
+	CheckInterval string `yaml:"checkInterval" json:"checkInterval" xml:"checkInterval"`
+	// 单项检查超时时间 默认2s
+	CheckTimeout string `yaml:"checkTimeout" json:"checkTimeout" xml:"checkTimeout"`
+	// 开启pprof 默认false, 环境变量SGT_PPROF_ENABLE=true时也会开启
+	PProfEnable bool `yaml:"pprofEnable" json:"pprofEnable" xml:"pprofEnable"`
+	// block profile采样率, 见runtime.SetBlockProfileRate 默认0不采样
+	BlockProfileRate int `yaml:"blockProfileRate" json:"blockProfileRate" xml:"blockProfileRate"`
+	// mutex profile采样比例, 见runtime.SetMutexProfileFraction 默认0不采样
+	MutexProfileFraction int `yaml:"mutexProfileFraction" json:"mutexProfileFraction" xml:"mutexProfileFraction"`
 }
 
 type ServiceConfig struct {
@@ -262,6 +415,8 @@ type ServiceConfig struct {
 	Svrs []*ServerConfig `yaml:"servers" json:"servers" xml:"servers"`
 	// 服务发现配置
 	Discovery *DiscoveryConfig `yaml:"discovery" json:"discovery" xml:"discovery"`
+	// 服务注册权重, 下游http客户端weighted_round_robin负载均衡使用 默认100
+	Weight int `yaml:"weight" json:"weight" xml:"weight"`
 	// 数据库配置
 	Databases []*DatabaseConfig `yaml:"databases" json:"databases" xml:"databases"`
 	// redis配置
@@ -276,6 +431,12 @@ type ServiceConfig struct {
 	KafkaProducers []*KafkaProducerConfig `yaml:"kafkaProducers" json:"kafkaProducers" xml:"kafkaProducers"`
 	// kafka consumer配置
 	KafkaConsumers []*KafkaConsumerConfig `yaml:"kafkaConsumers" json:"kafkaConsumers" xml:"kafkaConsumers"`
+	// 限流配置
+	RateLimits []*RateLimitConfig `yaml:"rateLimits" json:"rateLimits" xml:"rateLimits"`
+	// 优雅关闭配置
+	Shutdown *ShutdownConfig `yaml:"shutdown" json:"shutdown" xml:"shutdown"`
+	// 管理端口配置
+	Admin *AdminConfig `yaml:"admin" json:"admin" xml:"admin"`
 }
 
 func (c *ServiceConfig) GetDatabase(name string) *DatabaseConfig {
@@ -345,6 +506,15 @@ func (c *ServiceConfig) KafkaConsumer(name string) *KafkaConsumerConfig {
 	return nil
 }
 
+func (c *ServiceConfig) GetRateLimit(name string) *RateLimitConfig {
+	for _, rl := range c.RateLimits {
+		if rl.Name == name {
+			return rl
+		}
+	}
+	return nil
+}
+
 // ValidateServers 校验 servers 配置。
 // 空列表合法：纯消费者/定时任务等不对外暴露端口，仍可使用服务发现调用下游。
 // 非空时：每种 proto 至多一条，端口须合法。
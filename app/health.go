@@ -0,0 +1,104 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/wangshanqi84-gif/sagittarius/app/config"
+	"github.com/wangshanqi84-gif/sagittarius/cores/health"
+	"github.com/wangshanqi84-gif/sagittarius/cores/server"
+	"github.com/wangshanqi84-gif/sagittarius/logger"
+)
+
+const (
+	defaultCheckInterval = 5 * time.Second
+)
+
+var (
+	errNotRegistered = errors.New("service not registered")
+	errDraining      = errors.New("server draining")
+)
+
+func (r *router) Health() *health.Registry {
+	return r.health
+}
+
+// initHealth 创建健康检查, 返回就绪检查间隔
+func initHealth(ctx context.Context, cfg *config.AdminConfig) (*health.Registry, time.Duration) {
+	interval := defaultCheckInterval
+	var opts []health.Option
+	if cfg != nil {
+		if cfg.CheckInterval != "" {
+			if d, err := time.ParseDuration(cfg.CheckInterval); err == nil && d > 0 {
+				interval = d
+			} else {
+				logger.Gen(ctx, "admin checkInterval config error:%v", cfg.CheckInterval)
+			}
+		}
+		if cfg.CheckTimeout != "" {
+			if d, err := time.ParseDuration(cfg.CheckTimeout); err == nil && d > 0 {
+				opts = append(opts, health.Timeout(d))
+			} else {
+				logger.Gen(ctx, "admin checkTimeout config error:%v", cfg.CheckTimeout)
+			}
+		}
+	}
+	h := health.New(opts...)
+	h.OnChange(func(report *health.Report) {
+		if report.OK() {
+			logger.Gen(ctx, "health check ready")
+		} else {
+			logger.Gen(ctx, "health check not ready, failed:%s", strings.Join(report.Failed(), ","))
+		}
+	})
+	return h, interval
+}
+
+// serverName 服务名称server.{proto}, 用于健康检查和路由展示
+func (r *router) serverName(srv server.Server) string {
+	name := "server"
+	if t := reflect.TypeOf(srv); t != nil {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		// 如cores/http/server取http
+		segs := strings.Split(t.PkgPath(), "/")
+		if len(segs) >= 2 && segs[len(segs)-1] == "server" {
+			name += "." + segs[len(segs)-2]
+		}
+	}
+	if n := r.srvNames[name]; n > 0 {
+		r.srvNames[name]++
+		return fmt.Sprintf("%s.%d", name, n)
+	}
+	r.srvNames[name]++
+	return name
+}
+
+// registerServerCheck 可排空的服务注册就绪检查
+func (r *router) registerServerCheck(name string, srv server.Server) {
+	d, ok := srv.(server.Drainer)
+	if !ok {
+		return
+	}
+	r.health.Register(name, health.Readiness, func(ctx context.Context) error {
+		if !d.Ready() {
+			return errDraining
+		}
+		return nil
+	})
+}
+
+// registerDiscoveryCheck 服务注册状态检查
+func (r *router) registerDiscoveryCheck() {
+	r.health.Register("discovery", health.Dependency, func(ctx context.Context) error {
+		if !r.registered.Load() {
+			return errNotRegistered
+		}
+		return nil
+	})
+}
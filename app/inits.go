@@ -144,24 +144,33 @@ func initDiscovery(ctx context.Context, cfg *config.ServiceConfig) registry.Disc
 	return nil
 }
 
-func initMetric(ctx context.Context, fullName string, cfg []*config.ServerConfig) []metric.IMetric {
+func initMetric(ctx context.Context, fullName string, cfg *config.ServiceConfig) []metric.IMetric {
 	var mtrs []metric.IMetric
 	mtrs = append(mtrs,
 		local.InitMetric(ctx),
 		sentry.InitMetric(ctx, sentry.SetServerName(fullName)),
 	)
+	// 配置管理端口时pprof由管理端口提供
+	if cfg.Admin != nil && cfg.Admin.Port > 0 {
+		return mtrs
+	}
 	if strings.ToLower(env.GetEnv(env.SgtPProfEnable)) == "true" {
-		// 找到最大配置端口
+		// 找到最大占用端口, rpc服务未配置管理端口时还占用port+1提供metrics
 		port := 0
-		for _, c := range cfg {
-			if c.Port < port {
-				port = c.Port
+		for _, c := range cfg.Svrs {
+			used := c.Port
+			if c.Proto == registry.ProtoRPC {
+				used = c.Port + 1
+			}
+			if used > port {
+				port = used
 			}
 		}
 		if port == 0 {
 			port = 8801
 		} else {
-			port -= 1
+			// 使用最大占用端口+1, 避免与已配置端口及rpc metrics端口冲突
+			port += 1
 		}
 		mtrs = append(mtrs, pprof.InitMetric(ctx, pprof.SetPort(port)))
 	}
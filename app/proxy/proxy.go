@@ -10,7 +10,11 @@ import (
 
 	"github.com/wangshanqi84-gif/sagittarius/app"
 	"github.com/wangshanqi84-gif/sagittarius/app/config"
+	"github.com/wangshanqi84-gif/sagittarius/cores/breaker"
+	"github.com/wangshanqi84-gif/sagittarius/cores/health"
+	"github.com/wangshanqi84-gif/sagittarius/cores/hedge"
 	httpClient "github.com/wangshanqi84-gif/sagittarius/cores/http/client"
+	"github.com/wangshanqi84-gif/sagittarius/cores/ratelimit"
 	rpcClient "github.com/wangshanqi84-gif/sagittarius/cores/rpc/client"
 	"github.com/wangshanqi84-gif/sagittarius/db"
 	"github.com/wangshanqi84-gif/sagittarius/logger"
@@ -27,6 +31,11 @@ import (
 	"google.golang.org/grpc"
 )
 
+const (
+	// rocket默认慢调用阈值
+	defaultRocketSlowThreshold = time.Second
+)
+
 var (
 	_dbClient       = sync.Map{}
 	_redisClient    = sync.Map{}
@@ -35,6 +44,7 @@ var (
 	_rocketConsumer = sync.Map{}
 	_kafkaProducer  = sync.Map{}
 	_kafkaConsumer  = sync.Map{}
+	_rateLimiter    = sync.Map{}
 
 	_dbMutex             = sync.Mutex{}
 	_redisMutex          = sync.Mutex{}
@@ -43,6 +53,7 @@ var (
 	_rocketConsumerMutex = sync.Mutex{}
 	_kafkaProducerMutex  = sync.Mutex{}
 	_kafkaConsumerMutex  = sync.Mutex{}
+	_rateLimiterMutex    = sync.Mutex{}
 )
 
 // InitDBClient 初始化db客户端
@@ -86,15 +97,30 @@ func InitDBClient(name string, opts ...db.Option) (*db.Client, error) {
 	if cfg.Driver != "" {
 		opts = append(opts, db.DriverName(cfg.Driver))
 	}
-	opts = append(opts, db.Logger(logger.GetLogger()))
+	slow, err := parseDuration(cfg.SlowThreshold, 0)
+	if err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("app init db client, config slowthreshold, value:%s", cfg.SlowThreshold))
+	}
+	if slow > 0 {
+		opts = append(opts, db.SlowThreshold(slow))
+	}
+	opts = append(opts, db.Name(name), db.Logger(logger.GetLogger()))
 	c, err := db.NewClient(cfg.Master, cfg.Slaves, opts...)
 	if err != nil {
 		return nil, err
 	}
+	registerDBCheck(name, c)
 	_dbClient.Store(name, c)
 	return c, nil
 }
 
+// registerDBCheck db连接加入健康检查
+func registerDBCheck(name string, c *db.Client) {
+	app.Router().Health().Register("db."+name, health.Dependency, func(ctx context.Context) error {
+		return c.Ping()
+	})
+}
+
 // InitDBClientWithConfig 初始化mysql客户端
 func InitDBClientWithConfig(cfg *config.DatabaseConfig) (*db.Client, error) {
 	if cfg == nil {
@@ -135,11 +161,19 @@ func InitDBClientWithConfig(cfg *config.DatabaseConfig) (*db.Client, error) {
 	if cfg.Driver != "" {
 		opts = append(opts, db.DriverName(cfg.Driver))
 	}
-	opts = append(opts, db.Logger(logger.GetLogger()))
+	slow, err := parseDuration(cfg.SlowThreshold, 0)
+	if err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("app init db client, config slowthreshold, value:%s", cfg.SlowThreshold))
+	}
+	if slow > 0 {
+		opts = append(opts, db.SlowThreshold(slow))
+	}
+	opts = append(opts, db.Name(cfg.Name), db.Logger(logger.GetLogger()))
 	c, err := db.NewClient(cfg.Master, cfg.Slaves, opts...)
 	if err != nil {
 		return nil, err
 	}
+	registerDBCheck(cfg.Name, c)
 	_dbClient.Store(cfg.Name, c)
 	return c, nil
 }
@@ -194,10 +228,21 @@ func InitRedisClient(name string, opts ...redis.Option) (*redis.Client, error) {
 	if cfg.Password != "" {
 		opts = append(opts, redis.Password(cfg.Password))
 	}
+	slow, err := parseDuration(cfg.SlowThreshold, 0)
+	if err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("app init redis client, config slowthreshold, value:%s", cfg.SlowThreshold))
+	}
+	if slow > 0 {
+		opts = append(opts, redis.SlowThreshold(slow))
+	}
+	opts = append(opts, redis.Logger(logger.GetLogger()))
 	c, err := redis.NewClient(opts...)
 	if err != nil {
 		return nil, err
 	}
+	app.Router().Health().Register("redis."+name, health.Dependency, func(ctx context.Context) error {
+		return c.Ping(ctx).Err()
+	})
 	_redisClient.Store(name, c)
 	return c, nil
 }
@@ -242,13 +287,29 @@ func InitRPCClient(ctx context.Context, name string, opts ...rpcClient.Option) (
 			return nil, err
 		}
 	}
-	opts = append(opts, rpcClient.WithUnaryInterceptor(
-		rpcClient.RetryClientUnaryInterceptor(cfg.Retry),
+	ints := []grpc.UnaryClientInterceptor{rpcClient.RetryClientUnaryInterceptor(cfg.Retry)}
+	if cfg.Breaker != nil {
+		group, err := breakerGroup(name, cfg.Breaker)
+		if err != nil {
+			return nil, errors.WithMessage(err, fmt.Sprintf("app init rpc client, config breaker, name:%s", name))
+		}
+		ints = append(ints, rpcClient.BreakerClientUnaryInterceptor(group, cfg.Breaker.Scope))
+	}
+	ints = append(ints,
 		rpcClient.LangClientUnaryInterceptor(),
+		rpcClient.RequestIDClientUnaryInterceptor(),
 		rpcClient.TimeoutClientUnaryInterceptor(timeout),
 		rpcClient.TracingClientUnaryInterceptor(app.Router().Ctx(), app.Router().Tracer()),
-		gPrometheus.UnaryClientInterceptor),
+		gPrometheus.UnaryClientInterceptor,
 	)
+	opts = append(opts, rpcClient.WithUnaryInterceptor(ints...))
+	if cfg.Hedge != nil {
+		hc, err := hedgeConfig(cfg.Hedge)
+		if err != nil {
+			return nil, errors.WithMessage(err, fmt.Sprintf("app init rpc client, config hedge, name:%s", name))
+		}
+		opts = append(opts, rpcClient.WithHedging(name, hc))
+	}
 	c, err := rpcClient.DialContext(ctx, opts...)
 	if err != nil {
 		return nil, err
@@ -345,12 +406,50 @@ func InitHttpClientUseConfig(ctx context.Context, cfg *config.ClientConfig) (*ht
 	if cfg.Retry > 0 {
 		opts = append(opts, httpClient.WithRetry(cfg.Retry))
 	}
+	if cfg.RetryPolicy != nil {
+		policy, err := retryPolicy(cfg.RetryPolicy)
+		if err != nil {
+			return nil, errors.WithMessage(err, fmt.Sprintf("app init http client, config retryPolicy, name:%s", fullName))
+		}
+		opts = append(opts, httpClient.WithRetryPolicy(policy))
+	}
+	if cfg.Balancer != "" {
+		opts = append(opts, httpClient.WithBalancerName(cfg.Balancer))
+	}
+	if cfg.Outlier != nil {
+		outlier, err := outlierConfig(cfg.Outlier)
+		if err != nil {
+			return nil, errors.WithMessage(err, fmt.Sprintf("app init http client, config outlier, name:%s", fullName))
+		}
+		opts = append(opts, httpClient.WithOutlierDetection(outlier))
+	}
+	opts = append(opts, httpClient.WithName(fullName))
+	if cfg.Hedge != nil {
+		hc, err := hedgeConfig(cfg.Hedge)
+		if err != nil {
+			return nil, errors.WithMessage(err, fmt.Sprintf("app init http client, config hedge, name:%s", fullName))
+		}
+		opts = append(opts, httpClient.WithHedging(hc))
+	}
+	var breakerInterceptor httpClient.Interceptor
+	if cfg.Breaker != nil {
+		group, err := breakerGroup(fullName, cfg.Breaker)
+		if err != nil {
+			return nil, errors.WithMessage(err, fmt.Sprintf("app init http client, config breaker, name:%s", fullName))
+		}
+		breakerInterceptor = httpClient.BreakerInterceptor(group, cfg.Breaker.Scope)
+	}
 	opts = append(opts, httpClient.WithSyncTimeout(cfg.SyncTimeout))
 	opts = append(opts, httpClient.WithInterceptors(
 		httpClient.TracingInterceptor(app.Router().Ctx(), app.Router().Tracer()),
+		httpClient.MetricsInterceptor(fullName),
 		httpClient.SyncTimeoutInterceptor(),
 		httpClient.WithLangInterceptor(),
+		httpClient.RequestIDInterceptor(),
 	))
+	if breakerInterceptor != nil {
+		opts = append(opts, httpClient.WithInterceptors(breakerInterceptor))
+	}
 	c := httpClient.NewClient(ctx, opts...)
 	_client.Store(fullKey, c)
 	return c, nil
@@ -446,17 +545,145 @@ func InitHttpClient(ctx context.Context, name string, opts ...httpClient.Option)
 	if cfg.Retry > 0 {
 		opts = append(opts, httpClient.WithRetry(cfg.Retry))
 	}
+	if cfg.RetryPolicy != nil {
+		policy, err := retryPolicy(cfg.RetryPolicy)
+		if err != nil {
+			return nil, errors.WithMessage(err, fmt.Sprintf("app init http client, config retryPolicy, name:%s", name))
+		}
+		opts = append(opts, httpClient.WithRetryPolicy(policy))
+	}
+	if cfg.Balancer != "" {
+		opts = append(opts, httpClient.WithBalancerName(cfg.Balancer))
+	}
+	if cfg.Outlier != nil {
+		outlier, err := outlierConfig(cfg.Outlier)
+		if err != nil {
+			return nil, errors.WithMessage(err, fmt.Sprintf("app init http client, config outlier, name:%s", name))
+		}
+		opts = append(opts, httpClient.WithOutlierDetection(outlier))
+	}
+	opts = append(opts, httpClient.WithName(name))
+	if cfg.Hedge != nil {
+		hc, err := hedgeConfig(cfg.Hedge)
+		if err != nil {
+			return nil, errors.WithMessage(err, fmt.Sprintf("app init http client, config hedge, name:%s", name))
+		}
+		opts = append(opts, httpClient.WithHedging(hc))
+	}
+	var breakerInterceptor httpClient.Interceptor
+	if cfg.Breaker != nil {
+		group, err := breakerGroup(name, cfg.Breaker)
+		if err != nil {
+			return nil, errors.WithMessage(err, fmt.Sprintf("app init http client, config breaker, name:%s", name))
+		}
+		breakerInterceptor = httpClient.BreakerInterceptor(group, cfg.Breaker.Scope)
+	}
 	opts = append(opts, httpClient.WithSyncTimeout(cfg.SyncTimeout))
 	opts = append(opts, httpClient.WithInterceptors(
 		httpClient.TracingInterceptor(app.Router().Ctx(), app.Router().Tracer()),
+		httpClient.MetricsInterceptor(name),
 		httpClient.SyncTimeoutInterceptor(),
 		httpClient.WithLangInterceptor(),
+		httpClient.RequestIDInterceptor(),
 	))
+	if breakerInterceptor != nil {
+		opts = append(opts, httpClient.WithInterceptors(breakerInterceptor))
+	}
 	c := httpClient.NewClient(ctx, opts...)
 	_client.Store(fullKey, c)
 	return c, nil
 }
 
+// parseDuration 解析时长配置, 未配置时返回def
+func parseDuration(value string, def time.Duration) (time.Duration, error) {
+	if value == "" {
+		return def, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// retryPolicy 转换http重试策略配置
+func retryPolicy(cfg *config.RetryPolicyConfig) (*httpClient.RetryPolicy, error) {
+	policy := &httpClient.RetryPolicy{
+		Multiplier:       cfg.Multiplier,
+		Jitter:           cfg.Jitter,
+		StatusCodes:      cfg.StatusCodes,
+		Methods:          cfg.Methods,
+		BudgetRatio:      cfg.BudgetRatio,
+		BudgetMinRetries: cfg.BudgetMinRetries,
+	}
+	var err error
+	if policy.Backoff, err = parseDuration(cfg.Backoff, 0); err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("value:%s", cfg.Backoff))
+	}
+	if policy.MaxBackoff, err = parseDuration(cfg.MaxBackoff, 0); err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("value:%s", cfg.MaxBackoff))
+	}
+	if policy.MaxElapsed, err = parseDuration(cfg.MaxElapsed, 0); err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("value:%s", cfg.MaxElapsed))
+	}
+	return policy, nil
+}
+
+// hedgeConfig 转换对冲请求配置
+func hedgeConfig(cfg *config.HedgeConfig) (*hedge.Config, error) {
+	hc := &hedge.Config{
+		UseP95:          cfg.UseP95,
+		MaxHedges:       cfg.MaxHedges,
+		Methods:         cfg.Methods,
+		BudgetRatio:     cfg.BudgetRatio,
+		BudgetMinHedges: cfg.BudgetMinHedges,
+	}
+	var err error
+	if hc.Delay, err = parseDuration(cfg.Delay, 0); err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("value:%s", cfg.Delay))
+	}
+	return hc, nil
+}
+
+// breakerGroup 根据配置创建熔断器组
+func breakerGroup(name string, cfg *config.BreakerConfig) (*breaker.Group, error) {
+	bc := breaker.Config{
+		Buckets:          cfg.Buckets,
+		MinRequests:      cfg.MinRequests,
+		ErrorRate:        cfg.ErrorRate,
+		SlowCallRate:     cfg.SlowCallRate,
+		HalfOpenRequests: cfg.HalfOpenRequests,
+	}
+	var err error
+	if bc.Window, err = parseDuration(cfg.Window, 0); err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("value:%s", cfg.Window))
+	}
+	if bc.SlowCall, err = parseDuration(cfg.SlowCall, 0); err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("value:%s", cfg.SlowCall))
+	}
+	if bc.OpenTimeout, err = parseDuration(cfg.OpenTimeout, 0); err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("value:%s", cfg.OpenTimeout))
+	}
+	return breaker.NewGroup(name, bc), nil
+}
+
+// outlierConfig 转换http被动健康检查配置
+func outlierConfig(cfg *config.OutlierConfig) (*httpClient.OutlierConfig, error) {
+	outlier := &httpClient.OutlierConfig{
+		ConsecutiveFailures: cfg.ConsecutiveFailures,
+		ErrorRate:           cfg.ErrorRate,
+		MinRequests:         cfg.MinRequests,
+		MaxEjectionPercent:  cfg.MaxEjectionPercent,
+	}
+	var err error
+	if outlier.Interval, err = parseDuration(cfg.Interval, 0); err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("value:%s", cfg.Interval))
+	}
+	if outlier.BaseEjectionTime, err = parseDuration(cfg.BaseEjectionTime, 0); err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("value:%s", cfg.BaseEjectionTime))
+	}
+	if outlier.MaxEjectionTime, err = parseDuration(cfg.MaxEjectionTime, 0); err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("value:%s", cfg.MaxEjectionTime))
+	}
+	return outlier, nil
+}
+
 // InitRocketProducer 初始化rocket producer
 func InitRocketProducer(ctx context.Context, name string, opts ...producer.Option) (*producer.Producer, error) {
 	_rocketProducerMutex.Lock()
@@ -503,9 +730,16 @@ func InitRocketProducer(ctx context.Context, name string, opts ...producer.Optio
 			SecurityToken: cfg.SecurityToken,
 		}))
 	}
+	slow, err := parseDuration(cfg.SlowThreshold, defaultRocketSlowThreshold)
+	if err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("app init rocket producer, config slowthreshold, value:%s", cfg.SlowThreshold))
+	}
 	opts = append(opts,
 		producer.WithTracer(app.Router().Tracer()),
-		producer.WithInterceptors([]primitive.Interceptor{producer.LogInterceptor(logger.GetLogger())}),
+		producer.WithInterceptors([]primitive.Interceptor{
+			producer.MetricsInterceptor(name, slow, logger.GetLogger()),
+			producer.LogInterceptor(logger.GetLogger()),
+		}),
 	)
 	p, err := producer.NewProducer(ctx, opts...)
 	if err != nil {
@@ -560,9 +794,16 @@ func InitRocketConsumer(ctx context.Context, name string, opts ...consumer.Optio
 	if cfg.Expression == "" {
 		cfg.Expression = "*"
 	}
+	slow, err := parseDuration(cfg.SlowThreshold, defaultRocketSlowThreshold)
+	if err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("app init rocket consumer, config slowthreshold, value:%s", cfg.SlowThreshold))
+	}
 	opts = append(opts,
 		consumer.WithTracer(app.Router().Tracer()),
-		consumer.WithInterceptors([]primitive.Interceptor{consumer.LogInterceptor(logger.GetLogger())}),
+		consumer.WithInterceptors([]primitive.Interceptor{
+			consumer.MetricsInterceptor(slow, logger.GetLogger()),
+			consumer.LogInterceptor(logger.GetLogger()),
+		}),
 		consumer.WithFrom(cfg.From),
 		consumer.WithGoroutineNums(runtime.NumCPU()*5),
 		consumer.WithGroupName(cfg.GroupName),
@@ -625,12 +866,21 @@ func InitKafkaProducer(name string, opts ...kafka.ProducerOption) (*kafka.Produc
 	if cfg.Mode != "" {
 		opts = append(opts, kafka.ProducerModel(cfg.Mode))
 	}
+	slow, err := parseDuration(cfg.SlowThreshold, 0)
+	if err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("app init kafka producer, config slowthreshold, value:%s", cfg.SlowThreshold))
+	}
+	if slow > 0 {
+		opts = append(opts, kafka.ProducerSlowThreshold(slow))
+	}
+	opts = append(opts, kafka.ProducerName(name), kafka.ProducerLogger(logger.GetLogger()))
 	clientID := app.Router().Service().ServiceName + ":" + app.Router().Service().ID
 	opts = append(opts, kafka.ProducerClientID(clientID))
 	p, err := kafka.NewProducer(app.Router().Ctx(), brokers, opts...)
 	if err != nil {
 		return nil, err
 	}
+	app.Router().Health().Register("kafka.producer."+name, health.Dependency, p.Ping)
 	_kafkaProducer.Store(name, p)
 	return p, nil
 }
@@ -697,6 +947,14 @@ func InitKafkaConsumer(name string, opts ...kafka.ConsumerOption) (*kafka.Consum
 	if !cfg.AutoCommit {
 		opts = append(opts, kafka.ConsumerAutoCommit(cfg.AutoCommit))
 	}
+	slow, err := parseDuration(cfg.SlowThreshold, 0)
+	if err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("app init kafka consumer, config slowthreshold, value:%s", cfg.SlowThreshold))
+	}
+	if slow > 0 {
+		opts = append(opts, kafka.ConsumerSlowThreshold(slow))
+	}
+	opts = append(opts, kafka.ConsumerLogger(logger.GetLogger()))
 	clientID := app.Router().Service().ServiceName + ":" + app.Router().Service().ID
 	opts = append(opts, kafka.ConsumerClientID(clientID))
 	topicMap := make(map[string]string)
@@ -707,6 +965,51 @@ func InitKafkaConsumer(name string, opts ...kafka.ConsumerOption) (*kafka.Consum
 	if err != nil {
 		return nil, err
 	}
+	app.Router().Health().Register("kafka.consumer."+name, health.Dependency, c.Ping)
 	_kafkaConsumer.Store(name, c)
 	return c, nil
 }
+
+// InitRateLimiter 初始化限流器, 返回限流器及其配置
+func InitRateLimiter(name string) (ratelimit.Limiter, *config.RateLimitConfig, error) {
+	_rateLimiterMutex.Lock()
+	defer _rateLimiterMutex.Unlock()
+	baseCfg, err := app.Router().Config()
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg := baseCfg.GetRateLimit(name)
+	if cfg == nil {
+		return nil, nil, errors.New(fmt.Sprintf("app init rate limiter, config is nil, name:%s", name))
+	}
+	if l, has := _rateLimiter.Load(name); has {
+		return l.(ratelimit.Limiter), cfg, nil
+	}
+	if cfg.Limit <= 0 {
+		return nil, nil, errors.New(fmt.Sprintf("app init rate limiter, limit must be positive, name:%s", name))
+	}
+	var l ratelimit.Limiter
+	switch strings.ToLower(cfg.Type) {
+	case "", ratelimit.TypeLocal:
+		l = ratelimit.NewTokenBucket(float64(cfg.Limit), cfg.Burst)
+	case ratelimit.TypeRedis:
+		window, err := parseDuration(cfg.Window, time.Second)
+		if err != nil {
+			return nil, nil, errors.WithMessage(err, fmt.Sprintf("app init rate limiter, config window, value:%s", cfg.Window))
+		}
+		// InitRedisClient自身加锁, 这里不会重入_rateLimiterMutex
+		rc, err := InitRedisClient(cfg.Redis)
+		if err != nil {
+			return nil, nil, err
+		}
+		prefix := cfg.Prefix
+		if prefix == "" {
+			prefix = "ratelimit:" + name
+		}
+		l = ratelimit.NewSlidingWindow(rc, prefix, cfg.Limit, window)
+	default:
+		return nil, nil, errors.New(fmt.Sprintf("app init rate limiter, type not support, type:%s", cfg.Type))
+	}
+	_rateLimiter.Store(name, l)
+	return l, cfg, nil
+}
@@ -2,13 +2,17 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -16,6 +20,8 @@ import (
 	"github.com/wangshanqi84-gif/sagittarius/configuration"
 	gCtx "github.com/wangshanqi84-gif/sagittarius/cores/context"
 	"github.com/wangshanqi84-gif/sagittarius/cores/env"
+	"github.com/wangshanqi84-gif/sagittarius/cores/health"
+	"github.com/wangshanqi84-gif/sagittarius/cores/http/client/balancer"
 	"github.com/wangshanqi84-gif/sagittarius/cores/metric"
 	"github.com/wangshanqi84-gif/sagittarius/cores/registry"
 	"github.com/wangshanqi84-gif/sagittarius/cores/server"
@@ -36,6 +42,16 @@ type router struct {
 	tracer    tracing.Tracer
 	metrics   []metric.IMetric
 	srvs      []server.Server
+	// names 与srvs对应的服务名称
+	names    []string
+	srvNames map[string]int
+	startAt  time.Time
+
+	health        *health.Registry
+	checkInterval time.Duration
+	registered    atomic.Bool
+	admin         *http.Server
+	adminMux      *http.ServeMux
 }
 
 func (r *router) Ctx() context.Context {
@@ -60,6 +76,11 @@ func (r *router) Tracer() tracing.Tracer {
 
 func (r *router) BindServer(srv ...server.Server) {
 	r.srvs = append(r.srvs, srv...)
+	for _, s := range srv {
+		name := r.serverName(s)
+		r.names = append(r.names, name)
+		r.registerServerCheck(name, s)
+	}
 }
 
 func (r *router) Service() *registry.Service {
@@ -101,8 +122,10 @@ func InitRouter(sd *config.ServiceDefine, opts ...config.Option) {
 			ServiceName: sd.ServiceName,
 		})
 		r = &router{
-			baseCtx: ctx,
-			cancel:  cancel,
+			baseCtx:  ctx,
+			cancel:   cancel,
+			srvNames: make(map[string]int),
+			startAt:  time.Now(),
 		}
 		// 确保服务器 GetTime 肯定会成功,因此忽略掉 error
 		u, _ := uuid.NewUUID()
@@ -129,6 +152,9 @@ func InitRouter(sd *config.ServiceDefine, opts ...config.Option) {
 			panic(err)
 		}
 		r.info.Hosts = hosts
+		if baseCfg.Weight > 0 {
+			r.info.Metadata = map[string]string{balancer.WeightKey: strconv.Itoa(baseCfg.Weight)}
+		}
 		// 生成fullname
 		fullName := fmt.Sprintf("%s.%s.%s", sd.Namespace, sd.Product, sd.ServiceName)
 		// 初始化日志
@@ -141,7 +167,14 @@ func InitRouter(sd *config.ServiceDefine, opts ...config.Option) {
 			panic(fmt.Sprintf("discovery %q configured but client init failed, check env", baseCfg.Discovery.Used))
 		}
 		// 初始化监控
-		r.metrics = initMetric(r.baseCtx, fullName, baseCfg.Svrs)
+		r.metrics = initMetric(r.baseCtx, fullName, &baseCfg)
+		// 初始化健康检查
+		r.health, r.checkInterval = initHealth(r.baseCtx, baseCfg.Admin)
+		if r.discovery != nil && len(r.info.Hosts) > 0 {
+			r.registerDiscoveryCheck()
+		}
+		// 初始化管理端口
+		r.admin, r.adminMux = initAdmin(r.baseCtx, baseCfg.Admin)
 		logger.Gen(r.baseCtx, "app %s init over", fullName)
 	})
 }
@@ -171,6 +204,16 @@ func Run() {
 			})
 		}
 	}
+	// 管理端口 先于服务启动, 探针可尽早访问
+	if r.admin != nil {
+		eg.Go(func() error {
+			logger.Gen(r.baseCtx, "admin server listen %s", r.admin.Addr)
+			if err := r.admin.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Gen(r.baseCtx, "admin server error:%v", err)
+			}
+			return nil
+		})
+	}
 	// 开启服务 & 监听stop
 	if len(r.srvs) > 0 {
 		for idx := 0; idx < len(r.srvs); idx++ {
@@ -190,8 +233,14 @@ func Run() {
 		if err := r.discovery.Register(r.baseCtx, r.info); err != nil {
 			panic(err)
 		}
+		r.registered.Store(true)
 		logger.Gen(r.baseCtx, "service %s register, %v", r.info.ServiceName, r.info)
 	}
+	// 就绪检查 结果同步到grpc健康检查
+	eg.Go(func() error {
+		r.health.Watch(r.baseCtx, r.checkInterval)
+		return nil
+	})
 	// 优雅关闭处理
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, []os.Signal{syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGINT}...)
@@ -207,22 +256,77 @@ func Run() {
 	_ = eg.Wait()
 }
 
+// shutdownTimes 排空时间和等待请求结束的超时时间
+func shutdownTimes(registered bool) (time.Duration, time.Duration) {
+	grace, timeout := time.Duration(0), 15*time.Second
+	if registered {
+		grace = 5 * time.Second
+	}
+	cfg, err := r.Config()
+	if err != nil || cfg.Shutdown == nil {
+		return grace, timeout
+	}
+	if cfg.Shutdown.Grace != "" {
+		if d, err := time.ParseDuration(cfg.Shutdown.Grace); err == nil {
+			grace = d
+		} else {
+			logger.Gen(r.baseCtx, "shutdown grace config error:%v", err)
+		}
+	}
+	if cfg.Shutdown.Timeout != "" {
+		if d, err := time.ParseDuration(cfg.Shutdown.Timeout); err == nil {
+			timeout = d
+		} else {
+			logger.Gen(r.baseCtx, "shutdown timeout config error:%v", err)
+		}
+	}
+	return grace, timeout
+}
+
+// ShutDown 优雅关闭
+// 1. 服务标记为未就绪 2. 服务发现摘除 3. 排空时间内继续处理请求 4. 等待处理中的请求结束并关闭服务
 func ShutDown() error {
-	sctx, scancel := context.WithTimeout(r.baseCtx, 15*time.Second)
-	defer scancel()
+	registered := r.discovery != nil && len(r.info.Hosts) > 0
+	grace, timeout := shutdownTimes(registered)
 
-	if r.discovery != nil && len(r.info.Hosts) > 0 {
-		ctx, cancel := context.WithTimeout(sctx, 5*time.Second)
-		defer cancel()
+	for _, srv := range r.srvs {
+		if d, ok := srv.(server.Drainer); ok {
+			d.Drain()
+		}
+	}
+	if registered {
+		ctx, cancel := context.WithTimeout(r.baseCtx, 5*time.Second)
+		r.registered.Store(false)
 		if err := r.discovery.Deregister(ctx, r.info); err != nil {
-			logger.Gen(sctx, "server shutdown, deregister error:%v", err)
+			logger.Gen(ctx, "server shutdown, deregister error:%v", err)
 		} else {
-			logger.Gen(sctx, "service %s deregister, %v", r.info.ServiceName, r.info)
+			logger.Gen(ctx, "service %s deregister, %v", r.info.ServiceName, r.info)
 		}
+		cancel()
 	}
+	if grace > 0 {
+		logger.Gen(r.baseCtx, "server draining, wait %v", grace)
+		time.Sleep(grace)
+	}
+
+	sctx, scancel := context.WithTimeout(r.baseCtx, timeout)
+	defer scancel()
+	var wg sync.WaitGroup
 	for _, srv := range r.srvs {
-		if err := srv.Stop(sctx); err != nil {
-			logger.Gen(sctx, "server stop error:%v", err)
+		wg.Add(1)
+		go func(srv server.Server) {
+			defer wg.Done()
+			if err := srv.Stop(sctx); err != nil {
+				logger.Gen(sctx, "server stop error:%v", err)
+			}
+		}(srv)
+	}
+	wg.Wait()
+	// 管理端口最后关闭, 排空期间探针仍可访问
+	if r.admin != nil {
+		if err := r.admin.Shutdown(sctx); err != nil {
+			logger.Gen(sctx, "admin server shutdown error:%v", err)
+			_ = r.admin.Close()
 		}
 	}
 	if r.tracer != nil {
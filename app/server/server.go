@@ -7,7 +7,10 @@ import (
 
 	"github.com/wangshanqi84-gif/sagittarius/app"
 	"github.com/wangshanqi84-gif/sagittarius/app/config"
+	"github.com/wangshanqi84-gif/sagittarius/app/proxy"
+	"github.com/wangshanqi84-gif/sagittarius/cores/health"
 	httpSrv "github.com/wangshanqi84-gif/sagittarius/cores/http/server"
+	"github.com/wangshanqi84-gif/sagittarius/cores/idempotency"
 	"github.com/wangshanqi84-gif/sagittarius/cores/registry"
 	rpcSrv "github.com/wangshanqi84-gif/sagittarius/cores/rpc/server"
 	ioSrv "github.com/wangshanqi84-gif/sagittarius/cores/socketio/server"
@@ -27,6 +30,9 @@ func mustServerPort(cfg *config.ServiceConfig, proto string) int {
 	return svr.Port
 }
 
+// InitRPCServer 初始化rpc server
+// 框架拦截器(RequestID/Recover/Lang/Prometheus/Tracing/Access)在前, opts中的拦截器(认证/限流/幂等等)在后执行,
+// 业务拦截器的panic会被recover, 日志带有请求ID
 func InitRPCServer(opts ...rpcSrv.Option) (*rpcSrv.Server, error) {
 	cfg, err := app.Router().Config()
 	if err != nil {
@@ -35,27 +41,40 @@ func InitRPCServer(opts ...rpcSrv.Option) (*rpcSrv.Server, error) {
 	// 初始化server
 	// 找到rpc配置
 	port := mustServerPort(cfg, registry.ProtoRPC)
+	opts = append([]rpcSrv.Option{
+		rpcSrv.UnaryInterceptor(
+			rpcSrv.RequestIDServerUnaryInterceptor(),
+			rpcSrv.RecoverServerInterceptor(logger.GetLogger()),
+			rpcSrv.LangServerUnaryInterceptor(),
+			grpcPrometheus.UnaryServerInterceptor,
+			rpcSrv.TracingServerUnaryInterceptor(app.Router().Tracer()),
+			rpcSrv.AccessServerUnaryInterceptor(logger.GetAccess(), !cfg.AccessRequestDisable),
+		),
+		rpcSrv.StreamInterceptor(
+			rpcSrv.RequestIDServerStreamInterceptor(),
+		),
+	}, opts...)
 	opts = append(opts, rpcSrv.Address(fmt.Sprintf(":%d", port)))
-	opts = append(opts, rpcSrv.UnaryInterceptor(
-		rpcSrv.RecoverServerInterceptor(logger.GetLogger()),
-		rpcSrv.LangServerUnaryInterceptor(),
-		grpcPrometheus.UnaryServerInterceptor,
-		rpcSrv.TracingServerUnaryInterceptor(app.Router().Tracer()),
-		rpcSrv.AccessServerUnaryInterceptor(logger.GetAccess(), !cfg.AccessRequestDisable),
-	))
 	opts = append(opts, rpcSrv.Options([]grpc.ServerOption{
 		grpc.MaxRecvMsgSize(1024 * 1024 * 16),
 	}...))
 	srv := rpcSrv.NewServer(opts...)
+	// 健康检查结果同步到grpc健康检查
+	app.Router().Health().OnChange(func(report *health.Report) {
+		srv.SetServing(report.OK())
+	})
 	grpcPrometheus.EnableHandlingTimeHistogram()
 	grpcPrometheus.Register(srv.Server)
 
-	go func() {
-		err := netHttp.ListenAndServe(fmt.Sprintf(":%d", port+1), promhttp.Handler())
-		if err != nil {
-			logger.Gen(app.Router().Ctx(), "init rpc server metrics error:%v", err)
-		}
-	}()
+	// 未配置管理端口时兼容原有port+1的metrics
+	if !app.Router().AdminEnabled() {
+		go func() {
+			err := netHttp.ListenAndServe(fmt.Sprintf(":%d", port+1), promhttp.Handler())
+			if err != nil {
+				logger.Gen(app.Router().Ctx(), "init rpc server metrics error:%v", err)
+			}
+		}()
+	}
 	return srv, nil
 }
 
@@ -75,7 +94,9 @@ func InitWebSocketServer(opts ...wsSrv.Option) (*wsSrv.Engine, error) {
 	opts = append(options, opts...)
 	srv := wsSrv.NewServer(opts...)
 	srv.Use(
+		wsSrv.RequestIDHandler(),
 		wsSrv.PanicHandler(logger.GetLogger()),
+		wsSrv.MetricsHandler(),
 		wsSrv.TracingHandler(app.Router().Tracer()),
 		wsSrv.LogHandler(logger.GetAccess(), !cfg.AccessRequestDisable),
 	)
@@ -93,7 +114,9 @@ func InitSocketIOServer(opts ...ioSrv.Option) (*ioSrv.Engine, error) {
 	// 初始化server
 	srv := ioSrv.NewServer(opts...)
 	srv.Use(
+		ioSrv.RequestIDHandler(),
 		ioSrv.PanicHandler(logger.GetLogger()),
+		ioSrv.MetricsHandler(),
 		ioSrv.TracingHandler(app.Router().Tracer()),
 		ioSrv.WithLangHandler(),
 		ioSrv.LogHandler(logger.GetAccess(), !cfg.AccessRequestDisable),
@@ -108,14 +131,86 @@ func InitHttpServer(opts ...httpSrv.Option) (*httpSrv.Engine, error) {
 	}
 	// 初始化server
 	// 找到rpc配置
-	opts = append(opts, httpSrv.Addr(fmt.Sprintf(":%d", mustServerPort(cfg, registry.ProtoHTTP))))
+	svrCfg, err := cfg.ServerByProto(registry.ProtoHTTP)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, httpSrv.Addr(fmt.Sprintf(":%d", svrCfg.Port)))
+	if len(svrCfg.TrustedProxies) > 0 {
+		opts = append(opts, httpSrv.TrustedProxies(svrCfg.TrustedProxies...))
+	}
 	srv := httpSrv.New(opts...)
 	srv.Use(
+		httpSrv.RequestIDHandler(),
 		httpSrv.PanicHandler(logger.GetLogger()),
+		httpSrv.MetricsHandler(),
 		httpSrv.TracingHandler(app.Router().Tracer()),
 		httpSrv.LogHandler(logger.GetAccess(), !cfg.AccessRequestDisable),
 		httpSrv.WithLangHandler(),
 		httpSrv.SyncTimeoutHandler(logger.GetLogger()),
 	)
+	if svrCfg.OpenAPI != "" {
+		svc := app.Router().Service()
+		srv.ServeOpenAPI(svrCfg.OpenAPI,
+			httpSrv.OpenAPITitle(fmt.Sprintf("%s.%s", svc.Product, svc.ServiceName)),
+			httpSrv.OpenAPISwaggerUI(svrCfg.SwaggerUI),
+		)
+	}
 	return srv, nil
 }
+
+// HttpRateLimitHandler 按配置名称创建http限流中间件
+func HttpRateLimitHandler(name string) (func(*httpSrv.Context), error) {
+	l, cfg, err := proxy.InitRateLimiter(name)
+	if err != nil {
+		return nil, err
+	}
+	return httpSrv.RateLimitHandler(l, httpSrv.LimitKeyFunc(cfg.Key)), nil
+}
+
+// RPCRateLimitInterceptors 按配置名称创建grpc限流拦截器
+func RPCRateLimitInterceptors(name string) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor, error) {
+	l, cfg, err := proxy.InitRateLimiter(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyFunc := rpcSrv.LimitKeyFunc(cfg.Key)
+	return rpcSrv.RateLimitServerUnaryInterceptor(l, keyFunc), rpcSrv.RateLimitServerStreamInterceptor(l, keyFunc), nil
+}
+
+// WebSocketRateLimitHandler 按配置名称创建websocket限流中间件
+func WebSocketRateLimitHandler(name string) (func(*wsSrv.Context), error) {
+	l, cfg, err := proxy.InitRateLimiter(name)
+	if err != nil {
+		return nil, err
+	}
+	return wsSrv.RateLimitHandler(l, wsSrv.LimitKeyFunc(cfg.Key)), nil
+}
+
+func newIdempotencyGuard(redisName string, opts ...idempotency.Option) (*idempotency.Guard, error) {
+	rc, err := proxy.InitRedisClient(redisName)
+	if err != nil {
+		return nil, err
+	}
+	svc := app.Router().Service()
+	prefix := fmt.Sprintf("idempotency:%s.%s.%s", svc.Namespace, svc.Product, svc.ServiceName)
+	return idempotency.New(idempotency.NewRedisStore(rc, prefix), opts...), nil
+}
+
+// HttpIdempotencyHandler 使用指定redis创建http幂等中间件
+func HttpIdempotencyHandler(redisName string, opts ...idempotency.Option) (func(*httpSrv.Context), error) {
+	g, err := newIdempotencyGuard(redisName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return httpSrv.IdempotencyHandler(g), nil
+}
+
+// RPCIdempotencyInterceptor 使用指定redis创建grpc幂等拦截器
+func RPCIdempotencyInterceptor(redisName string, opts ...idempotency.Option) (grpc.UnaryServerInterceptor, error) {
+	g, err := newIdempotencyGuard(redisName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return rpcSrv.IdempotencyServerUnaryInterceptor(g), nil
+}
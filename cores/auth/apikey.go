@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"strings"
+)
+
+const (
+	defaultAPIKeyHeader = "X-API-Key"
+)
+
+type APIKeyOption func(*APIKeyAuthenticator)
+
+// APIKeyHeader 读取api key的header, 默认X-API-Key
+func APIKeyHeader(header string) APIKeyOption {
+	return func(a *APIKeyAuthenticator) {
+		a.header = header
+	}
+}
+
+// APIKey 添加一个静态api key及其身份
+func APIKey(key string, subject string, scopes ...string) APIKeyOption {
+	return func(a *APIKeyAuthenticator) {
+		a.keys[sha256.Sum256([]byte(key))] = &Principal{
+			Subject: subject,
+			Type:    TypeAPIKey,
+			Scopes:  scopes,
+		}
+	}
+}
+
+// APIKeyAuthenticator 静态api key认证, key以sha256保存, 查找与key内容无关的耗时
+type APIKeyAuthenticator struct {
+	header string
+	keys   map[[sha256.Size]byte]*Principal
+}
+
+func NewAPIKeyAuthenticator(opts ...APIKeyOption) *APIKeyAuthenticator {
+	a := &APIKeyAuthenticator{
+		header: defaultAPIKeyHeader,
+		keys:   make(map[[sha256.Size]byte]*Principal),
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+func (a *APIKeyAuthenticator) Authenticate(_ context.Context, r *Request) (*Principal, error) {
+	key := strings.TrimSpace(r.Header(a.header))
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+	p, has := a.keys[sha256.Sum256([]byte(key))]
+	if !has {
+		return nil, ErrInvalidAPIKey
+	}
+	cp := *p
+	return &cp, nil
+}
@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+const (
+	TypeJWT    = "jwt"
+	TypeAPIKey = "apikey"
+	TypeHMAC   = "hmac"
+)
+
+var (
+	// ErrNoCredentials 请求未携带该认证方式所需的凭证
+	ErrNoCredentials = errors.New("no credentials")
+	ErrInvalidToken  = errors.New("invalid token")
+	ErrInvalidAPIKey = errors.New("invalid api key")
+	ErrInvalidSign   = errors.New("invalid signature")
+	ErrSignExpired   = errors.New("signature expired")
+	ErrSignReplayed  = errors.New("signature replayed")
+	// ErrSignUnsupported 流式body的请求无法校验签名
+	ErrSignUnsupported = errors.New("signature unsupported for streaming request")
+)
+
+type principalKey struct{}
+
+// Principal 认证通过的调用方
+type Principal struct {
+	Subject string                 `json:"subject"`
+	Type    string                 `json:"type"`
+	Scopes  []string               `json:"scopes,omitempty"`
+	Claims  map[string]interface{} `json:"-"`
+}
+
+// HasScopes 是否拥有全部scope
+func (p *Principal) HasScopes(scopes ...string) bool {
+	for _, s := range scopes {
+		found := false
+		for _, ps := range p.Scopes {
+			if ps == s {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// Request 认证所需的请求信息, 由http/grpc各自构建
+// grpc请求Method固定为POST, Path为FullMethod, Body为确定性序列化(proto Deterministic)后的请求消息
+// Streaming为true时body不可用(http流式body, grpc stream)
+type Request struct {
+	Method    string
+	Path      string
+	Query     string
+	Header    func(key string) string
+	Body      []byte
+	Streaming bool
+}
+
+type Authenticator interface {
+	Authenticate(ctx context.Context, r *Request) (*Principal, error)
+}
+
+// AuthenticatorFunc 函数形式的Authenticator
+type AuthenticatorFunc func(ctx context.Context, r *Request) (*Principal, error)
+
+func (f AuthenticatorFunc) Authenticate(ctx context.Context, r *Request) (*Principal, error) {
+	return f(ctx, r)
+}
+
+// Any 依次尝试多种认证方式, 返回第一个成功的结果
+// 全部失败时返回第一个非ErrNoCredentials的错误
+func Any(auths ...Authenticator) Authenticator {
+	return AuthenticatorFunc(func(ctx context.Context, r *Request) (*Principal, error) {
+		err := ErrNoCredentials
+		for _, a := range auths {
+			p, e := a.Authenticate(ctx, r)
+			if e == nil {
+				return p, nil
+			}
+			if err == ErrNoCredentials && !errors.Is(e, ErrNoCredentials) {
+				err = e
+			}
+		}
+		return nil, err
+	})
+}
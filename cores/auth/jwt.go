@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pkg/errors"
+)
+
+var (
+	defaultJWTMethods = []string{
+		"HS256", "HS384", "HS512",
+		"RS256", "RS384", "RS512",
+		"PS256", "PS384", "PS512",
+		"ES256", "ES384", "ES512",
+	}
+)
+
+type JWTOption func(*JWTAuthenticator)
+
+// JWTMethods 允许的签名算法, 默认HS/RS/PS/ES全部
+func JWTMethods(methods ...string) JWTOption {
+	return func(a *JWTAuthenticator) {
+		a.methods = methods
+	}
+}
+
+func JWTIssuer(issuer string) JWTOption {
+	return func(a *JWTAuthenticator) {
+		a.issuer = issuer
+	}
+}
+
+func JWTAudience(audience string) JWTOption {
+	return func(a *JWTAuthenticator) {
+		a.audience = audience
+	}
+}
+
+// JWTLeeway 校验exp/nbf时允许的时钟偏差
+func JWTLeeway(d time.Duration) JWTOption {
+	return func(a *JWTAuthenticator) {
+		a.leeway = d
+	}
+}
+
+// JWTScopeClaim scope所在的claim, 支持空格分隔字符串或数组, 默认依次尝试scope/scp
+func JWTScopeClaim(claim string) JWTOption {
+	return func(a *JWTAuthenticator) {
+		a.scopeClaim = claim
+	}
+}
+
+// JWTHeader 从指定header读取token, 默认Authorization: Bearer
+func JWTHeader(header string) JWTOption {
+	return func(a *JWTAuthenticator) {
+		a.header = header
+	}
+}
+
+// JWTAuthenticator 校验JWT, 密钥由KeySource按kid/alg提供
+type JWTAuthenticator struct {
+	keys       KeySource
+	methods    []string
+	issuer     string
+	audience   string
+	leeway     time.Duration
+	scopeClaim string
+	header     string
+}
+
+func NewJWTAuthenticator(keys KeySource, opts ...JWTOption) *JWTAuthenticator {
+	a := &JWTAuthenticator{
+		keys:    keys,
+		methods: defaultJWTMethods,
+		header:  "Authorization",
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+func (a *JWTAuthenticator) token(r *Request) string {
+	v := strings.TrimSpace(r.Header(a.header))
+	if !strings.EqualFold(a.header, "Authorization") {
+		return v
+	}
+	if len(v) > 7 && strings.EqualFold(v[:7], "Bearer ") {
+		return strings.TrimSpace(v[7:])
+	}
+	return ""
+}
+
+func (a *JWTAuthenticator) Authenticate(ctx context.Context, r *Request) (*Principal, error) {
+	raw := a.token(r)
+	if raw == "" {
+		return nil, ErrNoCredentials
+	}
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(a.methods),
+		jwt.WithLeeway(a.leeway),
+		jwt.WithExpirationRequired(),
+	}
+	if a.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(a.issuer))
+	}
+	if a.audience != "" {
+		opts = append(opts, jwt.WithAudience(a.audience))
+	}
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return a.keys.Key(ctx, kid, t.Method.Alg())
+	}, opts...)
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidToken, err.Error())
+	}
+	sub, _ := claims.GetSubject()
+	return &Principal{
+		Subject: sub,
+		Type:    TypeJWT,
+		Scopes:  a.scopes(claims),
+		Claims:  claims,
+	}, nil
+}
+
+func (a *JWTAuthenticator) scopes(claims jwt.MapClaims) []string {
+	names := []string{"scope", "scp"}
+	if a.scopeClaim != "" {
+		names = []string{a.scopeClaim}
+	}
+	for _, name := range names {
+		switch v := claims[name].(type) {
+		case string:
+			return strings.Fields(v)
+		case []interface{}:
+			scopes := make([]string, 0, len(v))
+			for _, s := range v {
+				if str, ok := s.(string); ok {
+					scopes = append(scopes, str)
+				}
+			}
+			return scopes
+		}
+	}
+	return nil
+}
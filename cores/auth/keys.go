@@ -0,0 +1,259 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultJWKSRefresh = 10 * time.Minute
+	// jwksMinReload 遇到未知kid时的最小重新加载间隔, 防止伪造kid打爆密钥源
+	jwksMinReload = 30 * time.Second
+)
+
+var (
+	ErrKeyNotFound = errors.New("key not found")
+)
+
+// KeySource 按kid和算法提供JWT验签密钥
+// HS算法返回[]byte, RS/PS返回*rsa.PublicKey, ES返回*ecdsa.PublicKey
+type KeySource interface {
+	Key(ctx context.Context, kid string, alg string) (interface{}, error)
+}
+
+// StaticKey 固定密钥, 忽略kid
+type StaticKey struct {
+	key interface{}
+}
+
+func NewStaticKey(key interface{}) *StaticKey {
+	return &StaticKey{key: key}
+}
+
+func (sk *StaticKey) Key(_ context.Context, _ string, alg string) (interface{}, error) {
+	if !keyMatchAlg(sk.key, alg) {
+		return nil, ErrKeyNotFound
+	}
+	return sk.key, nil
+}
+
+// keyMatchAlg 防止算法混淆, 如用RSA公钥作为HMAC密钥
+func keyMatchAlg(key interface{}, alg string) bool {
+	switch key.(type) {
+	case []byte:
+		return strings.HasPrefix(alg, "HS")
+	case *rsa.PublicKey:
+		return strings.HasPrefix(alg, "RS") || strings.HasPrefix(alg, "PS")
+	case *ecdsa.PublicKey:
+		return strings.HasPrefix(alg, "ES")
+	}
+	return false
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+type JWKSOption func(*JWKS)
+
+// JWKSRefresh 定期刷新间隔, 默认10分钟
+func JWKSRefresh(d time.Duration) JWKSOption {
+	return func(j *JWKS) {
+		j.refresh = d
+	}
+}
+
+// JWKSHttpClient 加载url时使用的http客户端
+func JWKSHttpClient(c *http.Client) JWKSOption {
+	return func(j *JWKS) {
+		j.client = c
+	}
+}
+
+// JWKS 从文件或http(s)地址加载的密钥集, 缓存并定期刷新
+// 遇到未知kid时立即重新加载以支持密钥轮换
+type JWKS struct {
+	source  string
+	refresh time.Duration
+	client  *http.Client
+
+	mu       sync.RWMutex
+	keys     map[string]interface{}
+	loadedAt time.Time
+	loadMu   sync.Mutex
+}
+
+func NewJWKS(source string, opts ...JWKSOption) *JWKS {
+	j := &JWKS{
+		source:  source,
+		refresh: defaultJWKSRefresh,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(j)
+	}
+	return j
+}
+
+func (j *JWKS) Key(ctx context.Context, kid string, alg string) (interface{}, error) {
+	j.mu.RLock()
+	key, has := j.keys[kid]
+	stale := time.Since(j.loadedAt) > j.refresh
+	j.mu.RUnlock()
+
+	if !has || stale {
+		if err := j.reload(ctx, !has); err != nil && !has {
+			return nil, err
+		}
+		j.mu.RLock()
+		key, has = j.keys[kid]
+		j.mu.RUnlock()
+	}
+	if !has || !keyMatchAlg(key, alg) {
+		return nil, ErrKeyNotFound
+	}
+	return key, nil
+}
+
+// reload 重新加载密钥集, missing表示因未知kid触发
+func (j *JWKS) reload(ctx context.Context, missing bool) error {
+	j.loadMu.Lock()
+	defer j.loadMu.Unlock()
+
+	j.mu.RLock()
+	since := time.Since(j.loadedAt)
+	loaded := j.keys != nil
+	j.mu.RUnlock()
+	// 其他goroutine刚刚加载过
+	if loaded && (since < jwksMinReload || (!missing && since <= j.refresh)) {
+		return nil
+	}
+	bs, err := j.fetch(ctx)
+	if err != nil {
+		return err
+	}
+	keys, err := ParseJWKS(bs)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	j.keys = keys
+	j.loadedAt = time.Now()
+	j.mu.Unlock()
+	return nil
+}
+
+func (j *JWKS) fetch(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(j.source, "http://") && !strings.HasPrefix(j.source, "https://") {
+		return os.ReadFile(j.source)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("load jwks status:%d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// ParseJWKS 解析JWK Set, 返回kid到密钥的映射, 跳过非签名用途和不支持的密钥
+func ParseJWKS(data []byte) (map[string]interface{}, error) {
+	var set jwkSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, errors.WithMessage(err, "| json.Unmarshal jwks")
+	}
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, errors.WithMessage(err, fmt.Sprintf("| parse jwk kid:%s", k.Kid))
+		}
+		if key != nil {
+			keys[k.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve:%s", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "oct":
+		return base64.RawURLEncoding.DecodeString(k.K)
+	}
+	return nil, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	bs, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(bs), nil
+}
@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	redisgo "github.com/go-redis/redis/v8"
+)
+
+// RedisNonceStore 基于redis的nonce缓存, 多实例共享
+type RedisNonceStore struct {
+	cmd    redisgo.Cmdable
+	prefix string
+}
+
+// NewRedisNonceStore prefix为key前缀, 不同服务共用redis时应区分
+func NewRedisNonceStore(cmd redisgo.Cmdable, prefix string) *RedisNonceStore {
+	if prefix == "" {
+		prefix = "hmac_nonce"
+	}
+	return &RedisNonceStore{
+		cmd:    cmd,
+		prefix: prefix,
+	}
+}
+
+func (s *RedisNonceStore) Use(ctx context.Context, nonce string, ttl time.Duration) (bool, error) {
+	return s.cmd.SetNX(ctx, fmt.Sprintf("%s:%s", s.prefix, nonce), 1, ttl).Result()
+}
@@ -0,0 +1,250 @@
+package breaker
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	gErrors "github.com/wangshanqi84-gif/sagittarius/cores/errors"
+)
+
+const (
+	ScopeService = "service" // 整个下游服务共用一个熔断器
+	ScopeMethod  = "method"  // 每个接口/方法独立熔断
+)
+
+// State 熔断器状态
+type State int
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half_open"
+	}
+	return "unknown"
+}
+
+// ErrOpen 熔断器打开时返回, 业务可据此降级
+var ErrOpen = gErrors.New(503, "service circuit breaker is open").Lang("zh", "服务熔断中, 请稍后再试")
+
+// Config 熔断配置, 0值使用默认值
+type Config struct {
+	// 统计窗口 默认10s
+	Window time.Duration
+	// 窗口分桶数 默认10
+	Buckets int
+	// 窗口内请求数达到后才判断 默认20
+	MinRequests int
+	// 错误率达到后打开 默认0.5
+	ErrorRate float64
+	// 慢调用耗时, 0不统计慢调用
+	SlowCall time.Duration
+	// 慢调用比例达到后打开 默认0.5
+	SlowCallRate float64
+	// 打开后经过该时间进入半开 默认5s
+	OpenTimeout time.Duration
+	// 半开状态放行的探测请求数, 全部成功后关闭 默认5
+	HalfOpenRequests int
+}
+
+func (c Config) withDefaults() Config {
+	if c.Window <= 0 {
+		c.Window = 10 * time.Second
+	}
+	if c.Buckets <= 0 {
+		c.Buckets = 10
+	}
+	if c.MinRequests <= 0 {
+		c.MinRequests = 20
+	}
+	if c.ErrorRate <= 0 || c.ErrorRate > 1 {
+		c.ErrorRate = 0.5
+	}
+	if c.SlowCallRate <= 0 || c.SlowCallRate > 1 {
+		c.SlowCallRate = 0.5
+	}
+	if c.OpenTimeout <= 0 {
+		c.OpenTimeout = 5 * time.Second
+	}
+	if c.HalfOpenRequests <= 0 {
+		c.HalfOpenRequests = 5
+	}
+	return c
+}
+
+type bucket struct {
+	start    time.Time
+	requests int
+	failures int
+	slows    int
+}
+
+// Breaker 滑动窗口熔断器
+type Breaker struct {
+	name string
+	key  string
+	cfg  Config
+
+	mu       sync.Mutex
+	state    State
+	openedAt time.Time
+	buckets  []bucket
+	width    time.Duration
+	// 半开状态已放行和已成功的探测数
+	probes    int
+	successes int
+}
+
+func newBreaker(name, key string, cfg Config) *Breaker {
+	b := &Breaker{
+		name:    name,
+		key:     key,
+		cfg:     cfg,
+		buckets: make([]bucket, cfg.Buckets),
+		width:   cfg.Window / time.Duration(cfg.Buckets),
+	}
+	breakerState.WithLabelValues(name, key).Set(float64(StateClosed))
+	return b
+}
+
+// State 当前状态
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tryHalfOpen(time.Now())
+	return b.state
+}
+
+// Allow 是否放行请求, 放行时必须调用Report上报结果
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tryHalfOpen(time.Now())
+	switch b.state {
+	case StateOpen:
+		breakerRejects.WithLabelValues(b.name, b.key).Inc()
+		return ErrOpen
+	case StateHalfOpen:
+		if b.probes >= b.cfg.HalfOpenRequests {
+			breakerRejects.WithLabelValues(b.name, b.key).Inc()
+			return ErrOpen
+		}
+		b.probes++
+	}
+	return nil
+}
+
+// Report 上报请求结果, elapsed为请求耗时
+func (b *Breaker) Report(failed bool, elapsed time.Duration) {
+	slow := b.cfg.SlowCall > 0 && elapsed >= b.cfg.SlowCall
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	switch b.state {
+	case StateOpen:
+		return
+	case StateHalfOpen:
+		if failed || slow {
+			b.transit(StateOpen, now)
+			return
+		}
+		b.successes++
+		if b.successes >= b.cfg.HalfOpenRequests {
+			b.transit(StateClosed, now)
+		}
+		return
+	}
+	bk := b.bucket(now)
+	bk.requests++
+	if failed {
+		bk.failures++
+	}
+	if slow {
+		bk.slows++
+	}
+	var requests, failures, slows int
+	for i := range b.buckets {
+		if now.Sub(b.buckets[i].start) < b.cfg.Window {
+			requests += b.buckets[i].requests
+			failures += b.buckets[i].failures
+			slows += b.buckets[i].slows
+		}
+	}
+	if requests < b.cfg.MinRequests {
+		return
+	}
+	if float64(failures)/float64(requests) >= b.cfg.ErrorRate ||
+		(b.cfg.SlowCall > 0 && float64(slows)/float64(requests) >= b.cfg.SlowCallRate) {
+		b.transit(StateOpen, now)
+	}
+}
+
+// bucket 当前时间所在的桶, 过期的桶先清零, 需持有锁
+func (b *Breaker) bucket(now time.Time) *bucket {
+	start := now.Truncate(b.width)
+	bk := &b.buckets[int(start.UnixNano()/int64(b.width))%len(b.buckets)]
+	if !bk.start.Equal(start) {
+		*bk = bucket{start: start}
+	}
+	return bk
+}
+
+// tryHalfOpen 打开超时后进入半开, 需持有锁
+func (b *Breaker) tryHalfOpen(now time.Time) {
+	if b.state == StateOpen && now.Sub(b.openedAt) >= b.cfg.OpenTimeout {
+		b.transit(StateHalfOpen, now)
+	}
+}
+
+// transit 状态切换, 需持有锁
+func (b *Breaker) transit(state State, now time.Time) {
+	if b.state == state {
+		return
+	}
+	log.Printf("circuit breaker, name:%s, key:%s, state:%s -> %s\n", b.name, b.key, b.state, state)
+	b.state = state
+	b.probes, b.successes = 0, 0
+	switch state {
+	case StateOpen:
+		b.openedAt = now
+	case StateClosed:
+		for i := range b.buckets {
+			b.buckets[i] = bucket{}
+		}
+	}
+	breakerState.WithLabelValues(b.name, b.key).Set(float64(state))
+	breakerTransitions.WithLabelValues(b.name, b.key, state.String()).Inc()
+}
+
+// Group 按key管理熔断器, name为下游服务名
+type Group struct {
+	name     string
+	cfg      Config
+	breakers sync.Map
+}
+
+func NewGroup(name string, cfg Config) *Group {
+	return &Group{
+		name: name,
+		cfg:  cfg.withDefaults(),
+	}
+}
+
+// Get 获取key对应的熔断器, 不存在时创建
+func (g *Group) Get(key string) *Breaker {
+	if b, has := g.breakers.Load(key); has {
+		return b.(*Breaker)
+	}
+	b, _ := g.breakers.LoadOrStore(key, newBreaker(g.name, key, g.cfg))
+	return b.(*Breaker)
+}
@@ -0,0 +1,24 @@
+package breaker
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	breakerState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "circuit_breaker_state",
+		Help: "Current circuit breaker state (0 closed, 1 open, 2 half-open).",
+	}, []string{"name", "key"})
+	breakerTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "circuit_breaker_transitions_total",
+		Help: "Total number of circuit breaker state transitions by target state.",
+	}, []string{"name", "key", "state"})
+	breakerRejects = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "circuit_breaker_rejected_total",
+		Help: "Total number of requests rejected by an open circuit breaker.",
+	}, []string{"name", "key"})
+)
+
+func init() {
+	prometheus.MustRegister(breakerState, breakerTransitions, breakerRejects)
+}
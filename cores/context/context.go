@@ -2,7 +2,10 @@ package context
 
 import (
 	"context"
+	"strings"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type (
@@ -10,6 +13,7 @@ type (
 	clientTransportKey struct{}
 	clientTimeoutKey   struct{}
 	clientLangKey      struct{}
+	requestIDKey       struct{}
 	forContextKey      struct{}
 )
 
@@ -47,6 +51,40 @@ func FromLangClientContext(ctx context.Context) string {
 	return lang
 }
 
+// NewRequestIDContext 写入请求ID, 用于日志关联及向下游传递
+func NewRequestIDContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+func FromRequestIDContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	if !ok {
+		return ""
+	}
+	return id
+}
+
+// NewRequestID 生成请求ID
+func NewRequestID() string {
+	return strings.ReplaceAll(uuid.New().String(), "-", "")
+}
+
+// ValidRequestID 上游传入的请求ID长度不超过128且只包含可见ascii字符
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
 type TransData struct {
 	Endpoint    string `json:"host"`
 	Namespace   string `json:"namespace"`
@@ -41,6 +41,9 @@ const (
 	_uberCtxTimeoutKey    = "_uber_ctx_timeout_key"
 	_uberCtxLangKey       = "lang"
 	_uberCtxLangAcceptKey = "Accept-Language"
+	_uberCtxEncryptKey    = "_uber_ctx_encrypt_key"
+	_uberCtxRequestIDKey  = "x-request-id"
+	_uberCtxIdempotentKey = "idempotency-key"
 )
 
 func GetUberMeta(md Metadata) string {
@@ -89,3 +92,48 @@ func GetUberLangHeader(md Metadata) string {
 func SetUberLangHeader(md Metadata, lang string) {
 	md.Set(_uberCtxLangKey, lang)
 }
+
+func GetUberHttpEncryptHeader(h http.Header) bool {
+	return h.Get(_uberCtxEncryptKey) == "1"
+}
+
+func SetUberHttpEncryptHeader(h http.Header) {
+	h.Set(_uberCtxEncryptKey, "1")
+}
+
+// UberRequestIDKey 请求ID在http header/grpc metadata/mq属性中的key
+func UberRequestIDKey() string {
+	return _uberCtxRequestIDKey
+}
+
+func GetUberHttpRequestIDHeader(h http.Header) string {
+	return h.Get(_uberCtxRequestIDKey)
+}
+
+func SetUberHttpRequestIDHeader(h http.Header, id string) {
+	h.Set(_uberCtxRequestIDKey, id)
+}
+
+func GetUberRequestID(md Metadata) string {
+	return md.Get(_uberCtxRequestIDKey)
+}
+
+func SetUberRequestID(md Metadata, id string) {
+	md.MD[_uberCtxRequestIDKey] = []string{id}
+}
+
+func GetUberHttpIdempotencyHeader(h http.Header) string {
+	return h.Get(_uberCtxIdempotentKey)
+}
+
+func SetUberHttpIdempotencyHeader(h http.Header, key string) {
+	h.Set(_uberCtxIdempotentKey, key)
+}
+
+func GetUberIdempotencyKey(md Metadata) string {
+	return md.Get(_uberCtxIdempotentKey)
+}
+
+func SetUberIdempotencyKey(md Metadata, key string) {
+	md.MD[_uberCtxIdempotentKey] = []string{key}
+}
@@ -15,6 +15,27 @@ type Error struct {
 	code    int
 	message string
 	mapping map[string]string
+	details []fieldDetail
+}
+
+// Detail 字段级错误信息
+type Detail struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type fieldDetail struct {
+	field   string
+	message *Error
+	args    []interface{}
+}
+
+func (d fieldDetail) text(lang string) string {
+	msg := d.message.langMessage(lang)
+	if len(d.args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, d.args...)
 }
 
 func (e *Error) Error() string { return fmt.Sprintf("%d - %s", e.code, e.message) }
@@ -48,6 +69,48 @@ func (e *Error) Lang(lang string, message string) *Error {
 	return e
 }
 
+// WithDetail 返回附加了字段错误的副本, message通过Lang支持多语言, args用于格式化message
+func (e *Error) WithDetail(field string, message *Error, args ...interface{}) *Error {
+	ne := &Error{
+		code:    e.code,
+		message: e.message,
+		mapping: e.mapping,
+		details: make([]fieldDetail, 0, len(e.details)+1),
+	}
+	ne.details = append(ne.details, e.details...)
+	ne.details = append(ne.details, fieldDetail{
+		field:   field,
+		message: message,
+		args:    args,
+	})
+	return ne
+}
+
+func (e *Error) Details() []Detail {
+	if len(e.details) == 0 {
+		return nil
+	}
+	ds := make([]Detail, 0, len(e.details))
+	for _, d := range e.details {
+		ds = append(ds, Detail{
+			Field:   d.field,
+			Message: d.text(""),
+		})
+	}
+	return ds
+}
+
+func (e *Error) localize(lang string) *Error {
+	ne := New(e.code, e.langMessage(lang))
+	for _, d := range e.details {
+		ne.details = append(ne.details, fieldDetail{
+			field:   d.field,
+			message: New(d.message.code, d.text(lang)),
+		})
+	}
+	return ne
+}
+
 func (e *Error) Is(err error) bool {
 	if se := new(Error); errors.As(err, &se) {
 		return se.code == e.code
@@ -101,7 +164,7 @@ func Cause(err error, lang string) *Error {
 	}
 	if _, ok := err.(*Error); ok {
 		e := err.(*Error)
-		return New(e.code, e.langMessage(lang))
+		return e.localize(lang)
 	}
 	return New(UnknownCode, UnknownErrorMessage)
 }
@@ -120,7 +183,7 @@ func CauseWithDefault(err error, lang string, defCode int, defMessage string) *E
 	}
 	if _, ok := err.(*Error); ok {
 		e := err.(*Error)
-		return New(e.code, e.langMessage(lang))
+		return e.localize(lang)
 	}
 	return New(defCode, defMessage)
 }
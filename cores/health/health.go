@@ -0,0 +1,262 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTimeout  = 2 * time.Second
+	defaultCacheTTL = time.Second
+
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Kind 检查类型, 数值小的检查同时参与数值大的检查范围
+type Kind int
+
+const (
+	// Liveness 进程自身状态, 失败需要重启, 参与livez/healthz/readyz
+	Liveness Kind = iota
+	// Dependency 依赖组件状态(db/redis/kafka等), 参与healthz/readyz
+	Dependency
+	// Readiness 接收流量的状态(如排空中), 只参与readyz
+	Readiness
+)
+
+// Check 检查函数, 返回nil表示正常
+type Check func(ctx context.Context) error
+
+// Result 单项检查结果
+type Result struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report 检查报告
+type Report struct {
+	Status string             `json:"status"`
+	Checks map[string]*Result `json:"checks,omitempty"`
+}
+
+func (r *Report) OK() bool {
+	return r.Status == StatusOK
+}
+
+// Failed 失败的检查项, 按名称排序
+func (r *Report) Failed() []string {
+	var names []string
+	for name, res := range r.Checks {
+		if res.Status != StatusOK {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+type Option func(*Registry)
+
+// Timeout 单项检查超时时间 默认2s
+func Timeout(timeout time.Duration) Option {
+	return func(r *Registry) {
+		r.timeout = timeout
+	}
+}
+
+// CacheTTL 检查结果缓存时间, 避免探针频繁访问依赖 默认1s
+func CacheTTL(ttl time.Duration) Option {
+	return func(r *Registry) {
+		r.ttl = ttl
+	}
+}
+
+type entry struct {
+	kind  Kind
+	check Check
+
+	mu   sync.Mutex
+	last *Result
+	at   time.Time
+}
+
+// Registry 健康检查注册表, 各组件注册检查项, 由http探针和grpc健康检查共用
+type Registry struct {
+	timeout time.Duration
+	ttl     time.Duration
+
+	mu        sync.RWMutex
+	entries   map[string]*entry
+	listeners []func(*Report)
+	last      *Report
+}
+
+func New(opts ...Option) *Registry {
+	r := &Registry{
+		timeout: defaultTimeout,
+		ttl:     defaultCacheTTL,
+		entries: make(map[string]*entry),
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(r)
+		}
+	}
+	return r
+}
+
+// Register 注册检查项, 同名覆盖
+func (r *Registry) Register(name string, kind Kind, check Check) {
+	r.mu.Lock()
+	r.entries[name] = &entry{kind: kind, check: check}
+	r.mu.Unlock()
+}
+
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	delete(r.entries, name)
+	r.mu.Unlock()
+}
+
+// Run 并发执行kind范围内的检查
+func (r *Registry) Run(ctx context.Context, kind Kind) *Report {
+	r.mu.RLock()
+	entries := make(map[string]*entry, len(r.entries))
+	for name, e := range r.entries {
+		if e.kind <= kind {
+			entries[name] = e
+		}
+	}
+	r.mu.RUnlock()
+
+	report := &Report{Status: StatusOK, Checks: make(map[string]*Result, len(entries))}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for name, e := range entries {
+		wg.Add(1)
+		go func(name string, e *entry) {
+			defer wg.Done()
+			res := e.run(ctx, r.timeout, r.ttl)
+			mu.Lock()
+			report.Checks[name] = res
+			if res.Status != StatusOK {
+				report.Status = StatusFail
+			}
+			mu.Unlock()
+		}(name, e)
+	}
+	wg.Wait()
+	return report
+}
+
+func (r *Registry) Live(ctx context.Context) *Report {
+	return r.Run(ctx, Liveness)
+}
+
+func (r *Registry) Health(ctx context.Context) *Report {
+	return r.Run(ctx, Dependency)
+}
+
+func (r *Registry) Ready(ctx context.Context) *Report {
+	return r.Run(ctx, Readiness)
+}
+
+// OnChange 就绪状态变化时回调, 由Watch触发, Watch已有结果时立即回调一次
+func (r *Registry) OnChange(fn func(*Report)) {
+	r.mu.Lock()
+	r.listeners = append(r.listeners, fn)
+	last := r.last
+	r.mu.Unlock()
+	if last != nil {
+		fn(last)
+	}
+}
+
+// Watch 每隔interval检查一次就绪状态, 首次检查及状态变化时通知OnChange的回调, ctx结束时返回
+func (r *Registry) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	first, last := true, false
+	for {
+		report := r.Ready(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if first || report.OK() != last {
+			first, last = false, report.OK()
+			r.mu.Lock()
+			r.last = report
+			listeners := append([]func(*Report){}, r.listeners...)
+			r.mu.Unlock()
+			for _, fn := range listeners {
+				fn(report)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Handler 以json输出kind范围内的检查报告, 失败时返回503
+func (r *Registry) Handler(kind Kind) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		report := r.Run(req.Context(), kind)
+		code := http.StatusOK
+		if !report.OK() {
+			code = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
+
+// run 执行检查, ttl内复用上次结果, 并发调用时等待同一次执行
+func (e *entry) run(ctx context.Context, timeout time.Duration, ttl time.Duration) *Result {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.last != nil && time.Since(e.at) < ttl {
+		return e.last
+	}
+	start := time.Now()
+	cctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				done <- fmt.Errorf("check panic: %v", rec)
+			}
+		}()
+		done <- e.check(cctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-cctx.Done():
+		err = cctx.Err()
+	}
+	res := &Result{Status: StatusOK, Duration: time.Since(start).Truncate(time.Microsecond).String()}
+	if err != nil {
+		res.Status = StatusFail
+		res.Error = err.Error()
+	}
+	// 调用方取消导致的失败不缓存
+	if ctx.Err() == nil {
+		e.last, e.at = res, time.Now()
+	}
+	return res
+}
@@ -0,0 +1,184 @@
+package hedge
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// budgetBuckets 对冲预算统计窗口的秒数
+	budgetBuckets = 10
+	// samples p95统计的样本数
+	samples = 256
+	// minSamples 样本数达到后才使用p95
+	minSamples = 32
+
+	ResultSent   = "sent"             // 发出对冲请求
+	ResultWon    = "won"              // 对冲请求先于原请求成功
+	ResultBudget = "budget_exhausted" // 预算不足未发出
+)
+
+var hedgeRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "hedge_requests_total",
+	Help: "Total number of hedged requests by result.",
+}, []string{"name", "result"})
+
+func init() {
+	prometheus.MustRegister(hedgeRequests)
+}
+
+// Config 对冲配置, 0值使用默认值
+type Config struct {
+	// 原请求经过该时间未返回时发出对冲请求 默认100ms
+	Delay time.Duration
+	// 使用观测到的p95耗时作为延迟, 样本不足时使用Delay
+	UseP95 bool
+	// 最多对冲请求数 默认1
+	MaxHedges int
+	// 允许对冲的方法, 只应声明安全(无副作用)的方法
+	Methods []string
+	// 10s窗口内对冲数不超过 BudgetMinHedges + BudgetRatio*请求数
+	// BudgetRatio 默认0.1, 负数不限制
+	BudgetRatio float64
+	// BudgetMinHedges 默认0
+	BudgetMinHedges int
+}
+
+// Hedger 对冲延迟计算和预算控制
+type Hedger struct {
+	name string
+	cfg  Config
+
+	mu      sync.Mutex
+	ring    [samples]time.Duration
+	count   int
+	p95     time.Duration
+	buckets [budgetBuckets]struct {
+		sec      int64
+		requests int
+		hedges   int
+	}
+}
+
+// New name为下游服务名, 用于监控
+func New(name string, cfg Config) *Hedger {
+	if cfg.Delay <= 0 {
+		cfg.Delay = 100 * time.Millisecond
+	}
+	if cfg.MaxHedges <= 0 {
+		cfg.MaxHedges = 1
+	}
+	if cfg.BudgetRatio == 0 {
+		cfg.BudgetRatio = 0.1
+	}
+	if cfg.BudgetMinHedges < 0 {
+		cfg.BudgetMinHedges = 0
+	}
+	return &Hedger{
+		name: name,
+		cfg:  cfg,
+	}
+}
+
+// AllowMethod 是否允许对冲
+func (h *Hedger) AllowMethod(method string) bool {
+	for _, m := range h.cfg.Methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// MaxHedges 最多对冲请求数
+func (h *Hedger) MaxHedges() int {
+	return h.cfg.MaxHedges
+}
+
+// Delay 发出对冲请求前的等待时间
+func (h *Hedger) Delay() time.Duration {
+	if !h.cfg.UseP95 {
+		return h.cfg.Delay
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.count < minSamples {
+		return h.cfg.Delay
+	}
+	return h.p95
+}
+
+// Observe 记录成功请求的耗时
+func (h *Hedger) Observe(d time.Duration) {
+	if !h.cfg.UseP95 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.ring[h.count%samples] = d
+	h.count++
+	// 每minSamples个样本重新计算一次
+	if h.count%minSamples != 0 {
+		return
+	}
+	n := h.count
+	if n > samples {
+		n = samples
+	}
+	sorted := make([]time.Duration, n)
+	copy(sorted, h.ring[:n])
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	h.p95 = sorted[(n*95-1)/100]
+}
+
+func (h *Hedger) current(now time.Time) int {
+	sec := now.Unix()
+	idx := int(sec % budgetBuckets)
+	if h.buckets[idx].sec != sec {
+		h.buckets[idx].sec = sec
+		h.buckets[idx].requests = 0
+		h.buckets[idx].hedges = 0
+	}
+	return idx
+}
+
+// Request 记录一次可对冲的请求
+func (h *Hedger) Request() {
+	h.mu.Lock()
+	h.buckets[h.current(time.Now())].requests++
+	h.mu.Unlock()
+}
+
+// Allow 预算足够时记录一次对冲并返回true
+func (h *Hedger) Allow() bool {
+	if h.cfg.BudgetRatio < 0 {
+		hedgeRequests.WithLabelValues(h.name, ResultSent).Inc()
+		return true
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now()
+	idx := h.current(now)
+	var requests, hedges int
+	for _, bk := range h.buckets {
+		if now.Unix()-bk.sec < budgetBuckets {
+			requests += bk.requests
+			hedges += bk.hedges
+		}
+	}
+	if float64(hedges+1) > float64(h.cfg.BudgetMinHedges)+h.cfg.BudgetRatio*float64(requests) {
+		hedgeRequests.WithLabelValues(h.name, ResultBudget).Inc()
+		return false
+	}
+	h.buckets[idx].hedges++
+	hedgeRequests.WithLabelValues(h.name, ResultSent).Inc()
+	return true
+}
+
+// Won 记录对冲请求先于原请求成功
+func (h *Hedger) Won() {
+	hedgeRequests.WithLabelValues(h.name, ResultWon).Inc()
+}
@@ -2,15 +2,71 @@ package balancer
 
 import (
 	"context"
+	"strconv"
 
 	"github.com/wangshanqi84-gif/sagittarius/cores/registry"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// WeightKey 权重在registry.Service.Metadata中的key
+	WeightKey = "weight"
+	// DefaultWeight 未设置或设置错误时的权重
+	DefaultWeight = 100
 )
 
+var ErrNoAvailable = errors.New("no_available_node")
+
+// DoneInfo 请求结束信息
+type DoneInfo struct {
+	Err error
+	// StatusCode 应答状态码, 请求失败时为0
+	StatusCode int
+}
+
+// DoneFunc 请求结束时回调, 可以为nil
+type DoneFunc func(DoneInfo)
+
 type Balancer interface {
-	Pick(context.Context) (*registry.Service, error)
+	Pick(context.Context) (*registry.Service, DoneFunc, error)
 	Update(context.Context, []*registry.Service)
 }
 
 type Builder interface {
 	Build() Balancer
 }
+
+type hashKey struct{}
+
+// NewHashKeyContext 设置一致性hash使用的key
+func NewHashKeyContext(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, hashKey{}, key)
+}
+
+// HashKeyFromContext 获取一致性hash使用的key
+func HashKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(hashKey{}).(string)
+	return key
+}
+
+// Weight 节点权重, 取自Metadata的weight, 小于等于0或格式错误时使用默认值
+func Weight(node *registry.Service) int {
+	if node == nil || node.Metadata == nil {
+		return DefaultWeight
+	}
+	w, err := strconv.Atoi(node.Metadata[WeightKey])
+	if err != nil || w <= 0 {
+		return DefaultWeight
+	}
+	return w
+}
+
+// Key 节点唯一标识, 优先使用ID, 否则使用http地址
+func Key(node *registry.Service) string {
+	if node.ID != "" {
+		return node.ID
+	}
+	addr, _ := node.Endpoint(registry.ProtoHTTP)
+	return addr
+}
@@ -0,0 +1,109 @@
+package consistenthash
+
+import (
+	"context"
+	"hash/crc32"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/wangshanqi84-gif/sagittarius/cores/http/client/balancer"
+	"github.com/wangshanqi84-gif/sagittarius/cores/registry"
+)
+
+const (
+	Name = "consistent_hash"
+
+	defaultReplicas = 160
+)
+
+type Option func(o *options)
+
+type options struct {
+	replicas int
+}
+
+// Replicas 每个节点的虚拟节点数 默认160
+func Replicas(replicas int) Option {
+	return func(o *options) {
+		o.replicas = replicas
+	}
+}
+
+type ring struct {
+	hashes []uint32
+	nodes  map[uint32]*registry.Service
+	list   []*registry.Service
+}
+
+// Balancer 一致性hash, key通过balancer.NewHashKeyContext设置, 未设置时随机选取
+type Balancer struct {
+	replicas int
+	ring     atomic.Pointer[ring]
+}
+
+func (b *Balancer) Pick(ctx context.Context) (*registry.Service, balancer.DoneFunc, error) {
+	r := b.ring.Load()
+	if r == nil || len(r.list) == 0 {
+		return nil, nil, balancer.ErrNoAvailable
+	}
+	key := balancer.HashKeyFromContext(ctx)
+	if key == "" {
+		return r.list[rand.Intn(len(r.list))], nil, nil
+	}
+	h := crc32.ChecksumIEEE([]byte(key))
+	idx := sort.Search(len(r.hashes), func(i int) bool {
+		return r.hashes[i] >= h
+	})
+	if idx == len(r.hashes) {
+		idx = 0
+	}
+	return r.nodes[r.hashes[idx]], nil, nil
+}
+
+func (b *Balancer) Update(_ context.Context, service []*registry.Service) {
+	if len(service) == 0 {
+		return
+	}
+	r := &ring{
+		nodes: make(map[uint32]*registry.Service, len(service)*b.replicas),
+		list:  append([]*registry.Service(nil), service...),
+	}
+	for _, svc := range service {
+		key := balancer.Key(svc)
+		for i := 0; i < b.replicas; i++ {
+			h := crc32.ChecksumIEEE([]byte(key + "#" + strconv.Itoa(i)))
+			if _, has := r.nodes[h]; has {
+				continue
+			}
+			r.nodes[h] = svc
+			r.hashes = append(r.hashes, h)
+		}
+	}
+	sort.Slice(r.hashes, func(i, j int) bool {
+		return r.hashes[i] < r.hashes[j]
+	})
+	b.ring.Store(r)
+}
+
+type Builder struct {
+	replicas int
+}
+
+func (b *Builder) Build() balancer.Balancer {
+	return &Balancer{replicas: b.replicas}
+}
+
+func NewBuilder(opts ...Option) balancer.Builder {
+	option := options{
+		replicas: defaultReplicas,
+	}
+	for _, opt := range opts {
+		opt(&option)
+	}
+	if option.replicas <= 0 {
+		option.replicas = defaultReplicas
+	}
+	return &Builder{replicas: option.replicas}
+}
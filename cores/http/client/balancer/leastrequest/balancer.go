@@ -0,0 +1,91 @@
+package leastrequest
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+
+	"github.com/wangshanqi84-gif/sagittarius/cores/http/client/balancer"
+	"github.com/wangshanqi84-gif/sagittarius/cores/registry"
+)
+
+const Name = "least_request"
+
+type Option func(o *options)
+
+type options struct{}
+
+type node struct {
+	svc *registry.Service
+	// inflight 节点更新时共用, 保证更新前发出的请求结束时计数正确
+	inflight *atomic.Int64
+}
+
+// Balancer P2C, 随机选取两个节点, 取处理中请求数较少的节点
+type Balancer struct {
+	mu    sync.Mutex
+	nodes atomic.Pointer[[]*node]
+}
+
+func (b *Balancer) Pick(_ context.Context) (*registry.Service, balancer.DoneFunc, error) {
+	nodes := b.nodes.Load()
+	if nodes == nil || len(*nodes) == 0 {
+		return nil, nil, balancer.ErrNoAvailable
+	}
+	ns := *nodes
+	picked := ns[0]
+	if len(ns) > 1 {
+		i := rand.Intn(len(ns))
+		j := rand.Intn(len(ns) - 1)
+		if j >= i {
+			j++
+		}
+		picked = ns[i]
+		if ns[j].inflight.Load() < picked.inflight.Load() {
+			picked = ns[j]
+		}
+	}
+	picked.inflight.Add(1)
+	return picked.svc, func(balancer.DoneInfo) {
+		picked.inflight.Add(-1)
+	}, nil
+}
+
+func (b *Balancer) Update(_ context.Context, service []*registry.Service) {
+	if len(service) == 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// 保留已有节点的处理中请求数
+	old := make(map[string]*atomic.Int64)
+	if cur := b.nodes.Load(); cur != nil {
+		for _, n := range *cur {
+			old[balancer.Key(n.svc)] = n.inflight
+		}
+	}
+	nodes := make([]*node, 0, len(service))
+	for _, svc := range service {
+		inflight, has := old[balancer.Key(svc)]
+		if !has {
+			inflight = new(atomic.Int64)
+		}
+		nodes = append(nodes, &node{svc: svc, inflight: inflight})
+	}
+	b.nodes.Store(&nodes)
+}
+
+type Builder struct{}
+
+func (b *Builder) Build() balancer.Balancer {
+	return &Balancer{}
+}
+
+func NewBuilder(opts ...Option) balancer.Builder {
+	var option options
+	for _, opt := range opts {
+		opt(&option)
+	}
+	return &Builder{}
+}
@@ -3,36 +3,39 @@ package random
 import (
 	"context"
 	"math/rand"
+	"sync/atomic"
 
 	"github.com/wangshanqi84-gif/sagittarius/cores/http/client/balancer"
 	"github.com/wangshanqi84-gif/sagittarius/cores/registry"
-
-	"github.com/pkg/errors"
 )
 
-var ErrNoAvailable = errors.New("no_available_node")
+const Name = "random"
+
+var ErrNoAvailable = balancer.ErrNoAvailable
 
 type Option func(o *options)
 
 type options struct{}
 
 type Balancer struct {
-	nodes []*registry.Service
+	nodes atomic.Pointer[[]*registry.Service]
 }
 
-func (b *Balancer) Pick(_ context.Context) (*registry.Service, error) {
-	if len(b.nodes) == 0 {
-		return nil, ErrNoAvailable
+func (b *Balancer) Pick(_ context.Context) (*registry.Service, balancer.DoneFunc, error) {
+	nodes := b.nodes.Load()
+	if nodes == nil || len(*nodes) == 0 {
+		return nil, nil, ErrNoAvailable
 	}
-	cur := rand.Intn(len(b.nodes))
-	return b.nodes[cur], nil
+	cur := rand.Intn(len(*nodes))
+	return (*nodes)[cur], nil, nil
 }
 
 func (b *Balancer) Update(_ context.Context, service []*registry.Service) {
 	if len(service) == 0 {
 		return
 	}
-	b.nodes = service
+	nodes := append([]*registry.Service(nil), service...)
+	b.nodes.Store(&nodes)
 }
 
 type Builder struct{}
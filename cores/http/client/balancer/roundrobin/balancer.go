@@ -0,0 +1,56 @@
+package roundrobin
+
+import (
+	"context"
+	"math/rand"
+	"sync/atomic"
+
+	"github.com/wangshanqi84-gif/sagittarius/cores/http/client/balancer"
+	"github.com/wangshanqi84-gif/sagittarius/cores/registry"
+)
+
+const Name = "round_robin"
+
+type Option func(o *options)
+
+type options struct{}
+
+// Balancer 轮询
+type Balancer struct {
+	nodes atomic.Pointer[[]*registry.Service]
+	next  atomic.Uint64
+}
+
+func (b *Balancer) Pick(_ context.Context) (*registry.Service, balancer.DoneFunc, error) {
+	nodes := b.nodes.Load()
+	if nodes == nil || len(*nodes) == 0 {
+		return nil, nil, balancer.ErrNoAvailable
+	}
+	cur := (b.next.Add(1) - 1) % uint64(len(*nodes))
+	return (*nodes)[cur], nil, nil
+}
+
+func (b *Balancer) Update(_ context.Context, service []*registry.Service) {
+	if len(service) == 0 {
+		return
+	}
+	nodes := append([]*registry.Service(nil), service...)
+	b.nodes.Store(&nodes)
+}
+
+type Builder struct{}
+
+func (b *Builder) Build() balancer.Balancer {
+	bl := &Balancer{}
+	// 随机起点, 避免多个客户端同时从第一个节点开始
+	bl.next.Store(uint64(rand.Int63()))
+	return bl
+}
+
+func NewBuilder(opts ...Option) balancer.Builder {
+	var option options
+	for _, opt := range opts {
+		opt(&option)
+	}
+	return &Builder{}
+}
@@ -0,0 +1,83 @@
+package weighted
+
+import (
+	"context"
+	"sync"
+
+	"github.com/wangshanqi84-gif/sagittarius/cores/http/client/balancer"
+	"github.com/wangshanqi84-gif/sagittarius/cores/registry"
+)
+
+const Name = "weighted_round_robin"
+
+type Option func(o *options)
+
+type options struct{}
+
+type node struct {
+	svc     *registry.Service
+	weight  int
+	current int
+}
+
+// Balancer 平滑加权轮询, 权重取自Metadata的weight
+type Balancer struct {
+	mu    sync.Mutex
+	nodes []*node
+	total int
+}
+
+func (b *Balancer) Pick(_ context.Context) (*registry.Service, balancer.DoneFunc, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.nodes) == 0 {
+		return nil, nil, balancer.ErrNoAvailable
+	}
+	var best *node
+	for _, n := range b.nodes {
+		n.current += n.weight
+		if best == nil || n.current > best.current {
+			best = n
+		}
+	}
+	best.current -= b.total
+	return best.svc, nil, nil
+}
+
+func (b *Balancer) Update(_ context.Context, service []*registry.Service) {
+	if len(service) == 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// 保留已有节点的current, 避免更新后重新从头分配
+	old := make(map[string]*node, len(b.nodes))
+	for _, n := range b.nodes {
+		old[balancer.Key(n.svc)] = n
+	}
+	nodes := make([]*node, 0, len(service))
+	total := 0
+	for _, svc := range service {
+		n := &node{svc: svc, weight: balancer.Weight(svc)}
+		if o, has := old[balancer.Key(svc)]; has && o.weight == n.weight {
+			n.current = o.current
+		}
+		total += n.weight
+		nodes = append(nodes, n)
+	}
+	b.nodes, b.total = nodes, total
+}
+
+type Builder struct{}
+
+func (b *Builder) Build() balancer.Balancer {
+	return &Balancer{}
+}
+
+func NewBuilder(opts ...Option) balancer.Builder {
+	var option options
+	for _, opt := range opts {
+		opt(&option)
+	}
+	return &Builder{}
+}
@@ -16,11 +16,18 @@ import (
 	"strings"
 	"time"
 
+	gCtx "github.com/wangshanqi84-gif/sagittarius/cores/context"
+	"github.com/wangshanqi84-gif/sagittarius/cores/hedge"
 	"github.com/wangshanqi84-gif/sagittarius/cores/http/client/balancer"
+	"github.com/wangshanqi84-gif/sagittarius/cores/http/client/balancer/consistenthash"
+	"github.com/wangshanqi84-gif/sagittarius/cores/http/client/balancer/leastrequest"
 	"github.com/wangshanqi84-gif/sagittarius/cores/http/client/balancer/random"
+	"github.com/wangshanqi84-gif/sagittarius/cores/http/client/balancer/roundrobin"
+	"github.com/wangshanqi84-gif/sagittarius/cores/http/client/balancer/weighted"
 	"github.com/wangshanqi84-gif/sagittarius/cores/http/crypto"
 	"github.com/wangshanqi84-gif/sagittarius/cores/registry"
 
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"golang.org/x/net/http2"
 )
@@ -43,8 +50,12 @@ type clientOptions struct {
 	eps                 []string
 	watcher             registry.Watcher
 	balancerName        string
+	name                string
+	outlier             *OutlierConfig
 	interceptors        []Interceptor
 	retry               int
+	retryPolicy         *RetryPolicy
+	hedge               *hedge.Config
 }
 
 // WithWatcher 服务发现监听
@@ -54,13 +65,34 @@ func WithWatcher(watcher registry.Watcher) Option {
 	}
 }
 
-// WithBalancerName 负载均衡策略
+// WithBalancerName 负载均衡策略 random/round_robin/weighted_round_robin/least_request/consistent_hash 默认random
 func WithBalancerName(balancerName string) Option {
 	return func(o *clientOptions) {
 		o.balancerName = balancerName
 	}
 }
 
+// WithName 下游服务名, 用于日志和监控
+func WithName(name string) Option {
+	return func(o *clientOptions) {
+		o.name = name
+	}
+}
+
+// WithOutlierDetection 开启被动健康检查, 连续失败或错误率过高的节点临时摘除
+func WithOutlierDetection(cfg *OutlierConfig) Option {
+	return func(o *clientOptions) {
+		o.outlier = cfg
+	}
+}
+
+// WithHedging 开启对冲请求, Methods为空时只对冲GET/HEAD/OPTIONS
+func WithHedging(cfg *hedge.Config) Option {
+	return func(o *clientOptions) {
+		o.hedge = cfg
+	}
+}
+
 func WithTimeout(d time.Duration) Option {
 	return func(o *clientOptions) {
 		o.timeout = d
@@ -145,12 +177,20 @@ func WithEps(eps ...string) Option {
 	}
 }
 
+// WithRetry 最大重试次数, 使用默认重试策略
 func WithRetry(retry int) Option {
 	return func(o *clientOptions) {
 		o.retry = retry
 	}
 }
 
+// WithRetryPolicy 重试策略, MaxRetries为0时使用WithRetry的次数
+func WithRetryPolicy(policy *RetryPolicy) Option {
+	return func(o *clientOptions) {
+		o.retryPolicy = policy
+	}
+}
+
 type Client struct {
 	httpClient   *http.Client
 	syncTimeout  bool
@@ -158,7 +198,10 @@ type Client struct {
 	insecure     bool
 	resolver     *resolver
 	watcher      registry.Watcher
-	retry        int
+	name         string
+	retryPolicy  *RetryPolicy
+	retryBudget  *retryBudget
+	hedger       *hedge.Hedger
 }
 
 func createTransport(tlsCfg *tls.Config, opt *clientOptions) (http.RoundTripper, error) {
@@ -243,21 +286,11 @@ func NewClient(ctx context.Context, opts ...Option) *Client {
 			}
 		}
 	}
-	var (
-		r *resolver
-	)
-	if options.watcher != nil {
-		var builder balancer.Builder
-		switch options.balancerName {
-		case "random":
-			builder = random.NewBuilder()
-		default:
-			builder = random.NewBuilder()
-		}
-		r, _ = newResolver(ctx, options.watcher, builder, options.eps, insecure)
-	} else {
-		r, _ = newResolver(ctx, nil, random.NewBuilder(), options.eps, insecure)
+	var builder balancer.Builder = &triedBuilder{inner: newBalancerBuilder(options.balancerName)}
+	if options.outlier != nil {
+		builder = &outlierBuilder{inner: builder, upstream: options.name, cfg: options.outlier.withDefaults()}
 	}
+	r, _ := newResolver(ctx, options.watcher, builder, options.eps, insecure)
 	transport, err := createTransport(tlsCfg, &options)
 	if err != nil {
 		log.Printf("http client createTransport err:%v, use default transport.\n", err)
@@ -273,13 +306,63 @@ func NewClient(ctx context.Context, opts ...Option) *Client {
 		resolver:     r,
 		watcher:      options.watcher,
 		interceptors: options.interceptors,
-		retry:        options.retry,
+		name:         options.name,
+	}
+	if options.hedge != nil {
+		cfg := *options.hedge
+		if len(cfg.Methods) == 0 {
+			cfg.Methods = []string{http.MethodGet, http.MethodHead, http.MethodOptions}
+		}
+		c.hedger = hedge.New(options.name, cfg)
+	}
+	policy := options.retryPolicy
+	if policy == nil && options.retry > 0 {
+		policy = &RetryPolicy{}
+	}
+	if policy != nil {
+		policy = policy.withDefaults()
+		if policy.MaxRetries == 0 {
+			policy.MaxRetries = options.retry
+		}
+		if policy.MaxRetries > 0 {
+			c.retryPolicy = policy
+			c.retryBudget = &retryBudget{ratio: policy.BudgetRatio, min: policy.BudgetMinRetries}
+		}
 	}
 	return c
 }
 
+// newBalancerBuilder 按名称创建负载均衡, 未知名称使用random
+func newBalancerBuilder(name string) balancer.Builder {
+	switch name {
+	case "", random.Name:
+		return random.NewBuilder()
+	case roundrobin.Name:
+		return roundrobin.NewBuilder()
+	case weighted.Name:
+		return weighted.NewBuilder()
+	case leastrequest.Name:
+		return leastrequest.NewBuilder()
+	case consistenthash.Name:
+		return consistenthash.NewBuilder()
+	default:
+		log.Printf("http client unknown balancer:%s, use %s\n", name, random.Name)
+		return random.NewBuilder()
+	}
+}
+
+type routeKey struct{}
+
+// routeFromContext Req.Route设置的路由模板
+func routeFromContext(ctx context.Context) string {
+	route, _ := ctx.Value(routeKey{}).(string)
+	return route
+}
+
 type Req struct {
 	ctx        context.Context
+	hashKey    string
+	route      string
 	header     http.Header
 	queryParam url.Values
 	cookies    []*http.Cookie
@@ -300,6 +383,18 @@ func Request(ctx context.Context, uri string) *Req {
 	}
 }
 
+// HashKey 一致性hash负载均衡使用的key, 相同key的请求发往同一节点
+func (r *Req) HashKey(key string) *Req {
+	r.hashKey = key
+	return r
+}
+
+// Route 请求的路由模板, 如/users/{id}, 熔断等按接口统计的功能使用, 不能使用带参数的实际路径
+func (r *Req) Route(template string) *Req {
+	r.route = template
+	return r
+}
+
 func (r *Req) Crypto(c crypto.ICrypto) *Req {
 	r.crypto = c
 	return r
@@ -324,7 +419,8 @@ func (r *Req) SetHeaders(values map[string]string) *Req {
 	return r
 }
 
-func (r *Req) makeRequest() (*http.Request, error) {
+// makeRequest 请求绑定ctx, 每次重试/对冲都受调用方deadline和取消控制
+func (r *Req) makeRequest(ctx context.Context) (*http.Request, error) {
 	var (
 		err    error
 		req    *http.Request
@@ -350,7 +446,7 @@ func (r *Req) makeRequest() (*http.Request, error) {
 		}
 		reader = bytes.NewReader(bs)
 	}
-	if req, err = http.NewRequest(r.method, r.url, reader); err != nil {
+	if req, err = http.NewRequestWithContext(ctx, r.method, r.url, reader); err != nil {
 		return nil, err
 	}
 	for k, vs := range r.header {
@@ -361,6 +457,10 @@ func (r *Req) makeRequest() (*http.Request, error) {
 			req.Host = vs[0]
 		}
 	}
+	// 未指定时声明支持的压缩编码, 由decodeBody解压
+	if req.Header.Get("Accept-Encoding") == "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
 	q := req.URL.Query()
 	for k, v := range r.queryParam {
 		for _, vv := range v {
@@ -509,23 +609,26 @@ func (c *Client) CustomPost(r *Req, reqBody interface{}) (*http.Response, error)
 }
 
 func (c *Client) do(ctx context.Context, r *Req) (*http.Response, []byte, error) {
-	var (
-		resp *http.Response
-		err  error
-	)
-	// Send request
-	for att := 0; att <= c.retry; att++ {
-		var req *http.Request
-		req, err = r.makeRequest()
-		if err != nil {
-			continue
-		}
-		resp, err = doInterceptors(ctx, c, req)
-		if err != nil {
-			continue
-		}
-		break
+	// 重试策略允许重试的非幂等请求生成Idempotency-Key, 所有重试共用
+	if c.retryPolicy != nil && c.retryPolicy.allowMethod(r.method) &&
+		(r.method == http.MethodPost || r.method == http.MethodPatch) &&
+		gCtx.GetUberHttpIdempotencyHeader(r.header) == "" {
+		gCtx.SetUberHttpIdempotencyHeader(r.header, uuid.NewString())
+	}
+	if r.hashKey != "" {
+		ctx = balancer.NewHashKeyContext(ctx, r.hashKey)
+	}
+	if r.route != "" {
+		ctx = context.WithValue(ctx, routeKey{}, r.route)
+	}
+	// 记录已请求过的节点, 重试时优先选择其他节点
+	ctx = newTriedContext(ctx)
+	// Send request, 重试在拦截器链内部进行
+	req, err := r.makeRequest(ctx)
+	if err != nil {
+		return nil, nil, err
 	}
+	resp, err := doInterceptors(ctx, c, req)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -535,6 +638,18 @@ func (c *Client) do(ctx context.Context, r *Req) (*http.Response, []byte, error)
 	if err != nil {
 		return nil, nil, err
 	}
+	if body, err = decodeBody(resp, body); err != nil {
+		return nil, nil, err
+	}
+	// 服务端加密应答时使用请求的crypto解密
+	if r.crypto != nil && len(body) > 0 && gCtx.GetUberHttpEncryptHeader(resp.Header) {
+		var s string
+		s, err = r.crypto.Decrypt(string(body))
+		if err != nil {
+			return nil, nil, errors.WithMessage(err, "| crypto.Decrypt")
+		}
+		body = []byte(s)
+	}
 	// Reset resp.Body so it can be use again
 	resp.Body = io.NopCloser(bytes.NewBuffer(body))
 	return resp, body, nil
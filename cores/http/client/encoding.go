@@ -0,0 +1,69 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/flate"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+)
+
+const (
+	acceptEncoding = "br, zstd, gzip, deflate"
+)
+
+// decodeBody 按Content-Encoding逆序解压应答, 解压后移除相关header
+func decodeBody(resp *http.Response, body []byte) ([]byte, error) {
+	ce := resp.Header.Get("Content-Encoding")
+	if ce == "" || len(body) == 0 {
+		return body, nil
+	}
+	encodings := strings.Split(ce, ",")
+	for idx := len(encodings) - 1; idx >= 0; idx-- {
+		enc := strings.ToLower(strings.TrimSpace(encodings[idx]))
+		var (
+			r   io.Reader
+			err error
+		)
+		switch enc {
+		case "", "identity":
+			continue
+		case "gzip", "x-gzip":
+			r, err = gzip.NewReader(bytes.NewReader(body))
+		case "deflate":
+			// 标准为zlib格式, 部分服务端发送裸DEFLATE, zlib头错误时按裸DEFLATE解压
+			if r, err = zlib.NewReader(bytes.NewReader(body)); err == zlib.ErrHeader {
+				r, err = flate.NewReader(bytes.NewReader(body)), nil
+			}
+		case "br":
+			r = brotli.NewReader(bytes.NewReader(body))
+		case "zstd":
+			var zr *zstd.Decoder
+			zr, err = zstd.NewReader(bytes.NewReader(body), zstd.WithDecoderConcurrency(1))
+			if err == nil {
+				defer zr.Close()
+				r = zr
+			}
+		default:
+			return nil, fmt.Errorf("unsupported content encoding:%s", enc)
+		}
+		if err != nil {
+			return nil, errors.WithMessage(err, "| decode "+enc)
+		}
+		if body, err = io.ReadAll(r); err != nil {
+			return nil, errors.WithMessage(err, "| decode "+enc)
+		}
+	}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = int64(len(body))
+	resp.Uncompressed = true
+	return body, nil
+}
@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	otlog "github.com/opentracing/opentracing-go/log"
+)
+
+type hedgeResult struct {
+	resp    *http.Response
+	err     error
+	idx     int
+	elapsed time.Duration
+}
+
+// cancelReadCloser 应答body关闭时释放请求的context
+type cancelReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (r *cancelReadCloser) Close() error {
+	err := r.ReadCloser.Close()
+	r.cancel()
+	return err
+}
+
+// hedgeInvoke 原请求超过对冲延迟未返回时向其他节点发出对冲请求, 使用最先成功的应答并取消其他请求
+func hedgeInvoke(ctx context.Context, c *Client, req *http.Request) (*http.Response, error) {
+	h := c.hedger
+	if h == nil || !h.AllowMethod(req.Method) || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+		return invoke(ctx, c, req)
+	}
+	h.Request()
+	span := opentracing.SpanFromContext(ctx)
+	results := make(chan hedgeResult, h.MaxHedges()+1)
+	cancels := make([]context.CancelFunc, 0, h.MaxHedges()+1)
+	send := func(idx int) error {
+		attCtx, cancel := context.WithCancel(req.Context())
+		attReq := req.Clone(attCtx)
+		if idx > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				cancel()
+				return err
+			}
+			attReq.Body = body
+		}
+		cancels = append(cancels, cancel)
+		go func() {
+			start := time.Now()
+			resp, err := invoke(ctx, c, attReq)
+			results <- hedgeResult{resp: resp, err: err, idx: idx, elapsed: time.Since(start)}
+		}()
+		return nil
+	}
+	if err := send(0); err != nil {
+		return nil, err
+	}
+	timer := time.NewTimer(h.Delay())
+	defer timer.Stop()
+	sent, pending := 1, 1
+	var last *hedgeResult
+	for {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil && r.resp.StatusCode < http.StatusInternalServerError {
+				h.Observe(r.elapsed)
+				if r.idx > 0 {
+					h.Won()
+				}
+				for idx, cancel := range cancels {
+					if idx != r.idx {
+						cancel()
+					}
+				}
+				if last != nil && last.resp != nil {
+					drain(last.resp)
+				}
+				go discardHedges(results, pending)
+				r.resp.Body = &cancelReadCloser{ReadCloser: r.resp.Body, cancel: cancels[r.idx]}
+				return r.resp, nil
+			}
+			// 失败时等待其他请求, 全部失败时返回最后的结果
+			if last != nil {
+				if last.resp != nil {
+					drain(last.resp)
+				}
+				cancels[last.idx]()
+			}
+			last = &r
+			if pending > 0 {
+				continue
+			}
+			if r.err != nil {
+				cancels[r.idx]()
+				return nil, r.err
+			}
+			r.resp.Body = &cancelReadCloser{ReadCloser: r.resp.Body, cancel: cancels[r.idx]}
+			return r.resp, nil
+		case <-timer.C:
+			if sent > h.MaxHedges() || !h.Allow() {
+				continue
+			}
+			if err := send(sent); err != nil {
+				continue
+			}
+			if span != nil {
+				span.LogFields(
+					otlog.String("event", "http.hedge"),
+					otlog.Int("hedge", sent),
+				)
+			}
+			sent++
+			pending++
+			timer.Reset(h.Delay())
+		}
+	}
+}
+
+// discardHedges 丢弃被取消请求的应答
+func discardHedges(results chan hedgeResult, pending int) {
+	for ; pending > 0; pending-- {
+		if r := <-results; r.resp != nil {
+			drain(r.resp)
+		}
+	}
+}
@@ -3,11 +3,15 @@ package client
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/wangshanqi84-gif/sagittarius/cores/breaker"
 	gCtx "github.com/wangshanqi84-gif/sagittarius/cores/context"
+	"github.com/wangshanqi84-gif/sagittarius/cores/http/client/balancer"
 	"github.com/wangshanqi84-gif/sagittarius/cores/registry"
 
 	"github.com/opentracing/opentracing-go"
@@ -41,18 +45,19 @@ func doInterceptors(ctx context.Context, cc *Client, req *http.Request) (*http.R
 		}
 	}
 	if start != nil {
-		return start(ctx, cc, req, invoke)
+		return start(ctx, cc, req, retryInvoke)
 	}
-	return invoke(ctx, cc, req)
+	return retryInvoke(ctx, cc, req)
 }
 
 func invoke(ctx context.Context, c *Client, req *http.Request) (*http.Response, error) {
+	var done balancer.DoneFunc
 	if c.resolver != nil {
 		var (
 			node *registry.Service
 			err  error
 		)
-		if node, err = c.resolver.balancer.Pick(ctx); err != nil {
+		if node, done, err = c.resolver.balancer.Pick(ctx); err != nil {
 			return nil, errors.New("SERVER_NOT_FOUND")
 		}
 		if c.insecure {
@@ -62,7 +67,11 @@ func invoke(ctx context.Context, c *Client, req *http.Request) (*http.Response,
 		}
 		host, ok := node.Endpoint(registry.ProtoHTTP)
 		if !ok {
-			return nil, errors.New("no matching address found")
+			err = errors.New("no matching address found")
+			if done != nil {
+				done(balancer.DoneInfo{Err: err})
+			}
+			return nil, err
 		}
 		if strings.Contains(host, "://") {
 			ss := strings.Split(host, "://")
@@ -74,11 +83,33 @@ func invoke(ctx context.Context, c *Client, req *http.Request) (*http.Response,
 	}
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
+		if done != nil {
+			done(balancer.DoneInfo{Err: err})
+		}
 		return nil, err
 	}
+	if done != nil {
+		// 应答body关闭时请求结束
+		resp.Body = &doneReadCloser{ReadCloser: resp.Body, done: done, statusCode: resp.StatusCode}
+	}
 	return resp, nil
 }
 
+type doneReadCloser struct {
+	io.ReadCloser
+	done       balancer.DoneFunc
+	statusCode int
+	once       sync.Once
+}
+
+func (r *doneReadCloser) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(func() {
+		r.done(balancer.DoneInfo{StatusCode: r.statusCode})
+	})
+	return err
+}
+
 ///////////////////////////////////////////
 // 客户端拦截器
 ///////////////////////////////////////////
@@ -106,6 +137,8 @@ func TracingInterceptor(baseCtx context.Context, tracer opentracing.Tracer) Inte
 		if err := tracer.Inject(span.Context(), opentracing.HTTPHeaders, carrier); err != nil {
 			return nil, err
 		}
+		// 重试等事件记录在该span上
+		ctx = opentracing.ContextWithSpan(ctx, span)
 		return invoker(ctx, c, req)
 	}
 }
@@ -117,6 +150,16 @@ func WithLangInterceptor() Interceptor {
 	}
 }
 
+// RequestIDInterceptor 向下游传递请求ID
+func RequestIDInterceptor() Interceptor {
+	return func(ctx context.Context, c *Client, req *http.Request, invoker Invoker) (*http.Response, error) {
+		if id := gCtx.FromRequestIDContext(ctx); id != "" && gCtx.GetUberHttpRequestIDHeader(req.Header) == "" {
+			gCtx.SetUberHttpRequestIDHeader(req.Header, id)
+		}
+		return invoker(ctx, c, req)
+	}
+}
+
 func SyncTimeoutInterceptor() Interceptor {
 	return func(ctx context.Context, c *Client, req *http.Request, invoker Invoker) (*http.Response, error) {
 		if c.syncTimeout {
@@ -132,3 +175,25 @@ func SyncTimeoutInterceptor() Interceptor {
 		return invoker(ctx, c, req)
 	}
 }
+
+// BreakerInterceptor 熔断, scope为service时整个下游共用熔断器, 为method时按请求方法和Req.Route熔断
+// 未设置Req.Route的请求使用service熔断器, 避免按实际路径创建无限多的熔断器
+// 请求失败或5xx记为失败, 熔断器打开时返回breaker.ErrOpen
+func BreakerInterceptor(group *breaker.Group, scope string) Interceptor {
+	return func(ctx context.Context, c *Client, req *http.Request, invoker Invoker) (*http.Response, error) {
+		key := breaker.ScopeService
+		if scope == breaker.ScopeMethod {
+			if route := routeFromContext(ctx); route != "" {
+				key = req.Method + " " + route
+			}
+		}
+		b := group.Get(key)
+		if err := b.Allow(); err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		resp, err := invoker(ctx, c, req)
+		b.Report(err != nil || resp.StatusCode >= http.StatusInternalServerError, time.Since(start))
+		return resp, err
+	}
+}
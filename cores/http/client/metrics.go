@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// statusError 未收到应答(连接失败/超时等)时的status标签
+	statusError = "error"
+)
+
+var (
+	clientRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_client_requests_total",
+		Help: "Total number of HTTP requests sent by the client.",
+	}, []string{"upstream", "method", "route", "status"})
+	clientDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_client_request_duration_seconds",
+		Help:    "Histogram of HTTP request latency (seconds) observed by the client.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"upstream", "method", "route"})
+	clientInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "http_client_requests_in_flight",
+		Help: "Number of HTTP requests currently in flight from the client.",
+	}, []string{"upstream", "method", "route"})
+	clientErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_client_request_errors_total",
+		Help: "Total number of HTTP client requests failed or answered with status >= 400.",
+	}, []string{"upstream", "method", "route", "status"})
+)
+
+func init() {
+	prometheus.MustRegister(clientRequests, clientDuration, clientInFlight, clientErrors)
+}
+
+// MetricsInterceptor prometheus RED指标, upstream为下游服务名, route为Req.Route设置的路由模板, 未设置时为空
+// 包含重试在内只计数一次, 耗时为收到最终应答header的时间
+func MetricsInterceptor(upstream string) Interceptor {
+	return func(ctx context.Context, c *Client, req *http.Request, invoker Invoker) (*http.Response, error) {
+		method := req.Method
+		route := routeFromContext(ctx)
+		start := time.Now()
+		inFlight := clientInFlight.WithLabelValues(upstream, method, route)
+		inFlight.Inc()
+		resp, err := invoker(ctx, c, req)
+		inFlight.Dec()
+
+		status := statusError
+		if err == nil {
+			status = strconv.Itoa(resp.StatusCode)
+		}
+		clientRequests.WithLabelValues(upstream, method, route, status).Inc()
+		clientDuration.WithLabelValues(upstream, method, route).Observe(time.Since(start).Seconds())
+		if err != nil || resp.StatusCode >= http.StatusBadRequest {
+			clientErrors.WithLabelValues(upstream, method, route, status).Inc()
+		}
+		return resp, err
+	}
+}
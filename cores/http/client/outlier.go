@@ -0,0 +1,267 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/wangshanqi84-gif/sagittarius/cores/http/client/balancer"
+	"github.com/wangshanqi84-gif/sagittarius/cores/registry"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	reasonConsecutive = "consecutive_failures"
+	reasonErrorRate   = "error_rate"
+)
+
+var (
+	outlierEjected = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "http_client_outlier_ejected",
+		Help: "Whether the upstream node is currently ejected by outlier detection (1 ejected, 0 admitted).",
+	}, []string{"upstream", "node"})
+	outlierEjections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_client_outlier_ejections_total",
+		Help: "Total number of upstream node ejections by outlier detection.",
+	}, []string{"upstream", "node", "reason"})
+)
+
+func init() {
+	prometheus.MustRegister(outlierEjected, outlierEjections)
+}
+
+// OutlierConfig 被动健康检查配置, 0值使用默认值
+type OutlierConfig struct {
+	// 连续失败次数达到后摘除 默认5
+	ConsecutiveFailures int
+	// 统计周期内错误率达到后摘除 默认0.5
+	ErrorRate float64
+	// 统计周期内请求数达到后才按错误率判断 默认10
+	MinRequests int
+	// 错误率统计周期 默认10s
+	Interval time.Duration
+	// 首次摘除时长, 之后每次摘除翻倍 默认30s
+	BaseEjectionTime time.Duration
+	// 最长摘除时长 默认300s
+	MaxEjectionTime time.Duration
+	// 最多摘除节点比例(百分比) 默认50, 为100时全部摘除后请求返回balancer.ErrNoAvailable
+	MaxEjectionPercent int
+}
+
+func (c *OutlierConfig) withDefaults() OutlierConfig {
+	cfg := *c
+	if cfg.ConsecutiveFailures <= 0 {
+		cfg.ConsecutiveFailures = 5
+	}
+	if cfg.ErrorRate <= 0 || cfg.ErrorRate > 1 {
+		cfg.ErrorRate = 0.5
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = 10
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 10 * time.Second
+	}
+	if cfg.BaseEjectionTime <= 0 {
+		cfg.BaseEjectionTime = 30 * time.Second
+	}
+	if cfg.MaxEjectionTime < cfg.BaseEjectionTime {
+		cfg.MaxEjectionTime = 10 * cfg.BaseEjectionTime
+	}
+	if cfg.MaxEjectionPercent <= 0 || cfg.MaxEjectionPercent > 100 {
+		cfg.MaxEjectionPercent = 50
+	}
+	return cfg
+}
+
+// isFailure 请求失败或5xx应答记为失败
+func isFailure(info balancer.DoneInfo) bool {
+	return info.Err != nil || info.StatusCode >= http.StatusInternalServerError
+}
+
+type outlierNode struct {
+	svc *registry.Service
+
+	consecutive int
+	requests    int
+	failures    int
+	windowStart time.Time
+	ejections   int
+	ejected     bool
+	timer       *time.Timer
+}
+
+type outlierBuilder struct {
+	inner    balancer.Builder
+	upstream string
+	cfg      OutlierConfig
+}
+
+func (b *outlierBuilder) Build() balancer.Balancer {
+	return &outlierBalancer{
+		inner:    b.inner.Build(),
+		upstream: b.upstream,
+		cfg:      b.cfg,
+		nodes:    make(map[string]*outlierNode),
+	}
+}
+
+// outlierBalancer 被动健康检查, 根据请求结果摘除异常节点, 内部balancer只接收未摘除的节点
+type outlierBalancer struct {
+	inner    balancer.Balancer
+	upstream string
+	cfg      OutlierConfig
+
+	mu    sync.Mutex
+	nodes map[string]*outlierNode
+	order []string
+	// allEjected 全部节点被摘除, 内部balancer不接受空列表, Pick直接返回ErrNoAvailable
+	allEjected atomic.Bool
+}
+
+func (b *outlierBalancer) Pick(ctx context.Context) (*registry.Service, balancer.DoneFunc, error) {
+	if b.allEjected.Load() {
+		return nil, nil, balancer.ErrNoAvailable
+	}
+	node, done, err := b.inner.Pick(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	key := balancer.Key(node)
+	return node, func(info balancer.DoneInfo) {
+		if done != nil {
+			done(info)
+		}
+		b.report(key, info)
+	}, nil
+}
+
+func (b *outlierBalancer) Update(ctx context.Context, service []*registry.Service) {
+	if len(service) == 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	nodes := make(map[string]*outlierNode, len(service))
+	order := make([]string, 0, len(service))
+	for _, svc := range service {
+		key := balancer.Key(svc)
+		n, has := b.nodes[key]
+		if !has {
+			n = &outlierNode{svc: svc, windowStart: time.Now()}
+			outlierEjected.WithLabelValues(b.upstream, key).Set(0)
+		}
+		n.svc = svc
+		nodes[key] = n
+		order = append(order, key)
+	}
+	for key, n := range b.nodes {
+		if _, has := nodes[key]; !has {
+			if n.timer != nil {
+				n.timer.Stop()
+			}
+			outlierEjected.DeleteLabelValues(b.upstream, key)
+		}
+	}
+	b.nodes, b.order = nodes, order
+	b.refresh(ctx)
+}
+
+// refresh 将未摘除的节点同步给内部balancer, 需持有锁
+// 全部摘除时内部balancer会忽略空列表继续使用旧节点, 改为由Pick返回ErrNoAvailable直到有节点恢复
+func (b *outlierBalancer) refresh(ctx context.Context) {
+	healthy := make([]*registry.Service, 0, len(b.order))
+	for _, key := range b.order {
+		if n := b.nodes[key]; !n.ejected {
+			healthy = append(healthy, n.svc)
+		}
+	}
+	b.allEjected.Store(len(b.order) > 0 && len(healthy) == 0)
+	if len(healthy) == 0 {
+		return
+	}
+	b.inner.Update(ctx, healthy)
+}
+
+func (b *outlierBalancer) report(key string, info balancer.DoneInfo) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n, has := b.nodes[key]
+	if !has || n.ejected {
+		return
+	}
+	// 对冲等主动取消的请求不计入
+	if errors.Is(info.Err, context.Canceled) {
+		return
+	}
+	now := time.Now()
+	if now.Sub(n.windowStart) >= b.cfg.Interval {
+		// 无失败的统计周期逐步恢复摘除次数
+		if n.failures == 0 && n.ejections > 0 {
+			n.ejections--
+		}
+		n.requests, n.failures, n.windowStart = 0, 0, now
+	}
+	n.requests++
+	if !isFailure(info) {
+		n.consecutive = 0
+		return
+	}
+	n.failures++
+	n.consecutive++
+	switch {
+	case n.consecutive >= b.cfg.ConsecutiveFailures:
+		b.eject(key, n, reasonConsecutive)
+	case n.requests >= b.cfg.MinRequests && float64(n.failures)/float64(n.requests) >= b.cfg.ErrorRate:
+		b.eject(key, n, reasonErrorRate)
+	}
+}
+
+// eject 摘除节点, 摘除时长按摘除次数指数增长, 需持有锁
+func (b *outlierBalancer) eject(key string, n *outlierNode, reason string) {
+	ejected := 0
+	for _, node := range b.nodes {
+		if node.ejected {
+			ejected++
+		}
+	}
+	if (ejected+1)*100 > len(b.nodes)*b.cfg.MaxEjectionPercent {
+		log.Printf("http client outlier, upstream:%s, node:%s, reason:%s, skip ejection: max ejection percent reached\n",
+			b.upstream, key, reason)
+		return
+	}
+	n.ejections++
+	d := b.cfg.BaseEjectionTime << (n.ejections - 1)
+	if d > b.cfg.MaxEjectionTime || d <= 0 {
+		d = b.cfg.MaxEjectionTime
+	}
+	n.ejected = true
+	n.consecutive, n.requests, n.failures = 0, 0, 0
+	n.timer = time.AfterFunc(d, func() {
+		b.readmit(key, n)
+	})
+	outlierEjected.WithLabelValues(b.upstream, key).Set(1)
+	outlierEjections.WithLabelValues(b.upstream, key, reason).Inc()
+	log.Printf("http client outlier, upstream:%s, node:%s ejected, reason:%s, duration:%s\n",
+		b.upstream, key, reason, d)
+	b.refresh(context.Background())
+}
+
+func (b *outlierBalancer) readmit(key string, n *outlierNode) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.nodes[key] != n || !n.ejected {
+		return
+	}
+	n.ejected = false
+	n.timer = nil
+	n.windowStart = time.Now()
+	outlierEjected.WithLabelValues(b.upstream, key).Set(0)
+	log.Printf("http client outlier, upstream:%s, node:%s readmitted\n", b.upstream, key)
+	b.refresh(context.Background())
+}
@@ -0,0 +1,294 @@
+package client
+
+import (
+	"context"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	gCtx "github.com/wangshanqi84-gif/sagittarius/cores/context"
+
+	"github.com/opentracing/opentracing-go"
+	otlog "github.com/opentracing/opentracing-go/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// budgetBuckets 重试预算统计窗口的秒数
+	budgetBuckets = 10
+	// drainLimit 重试前丢弃的应答body上限
+	drainLimit = 4096
+
+	retryResultRetried = "retried"
+	retryResultBudget  = "budget_exhausted"
+)
+
+var clientRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "http_client_retries_total",
+	Help: "Total number of HTTP client retries, including retries denied by the retry budget.",
+}, []string{"upstream", "result"})
+
+func init() {
+	prometheus.MustRegister(clientRetries)
+}
+
+// RetryPolicy 重试策略, 0值使用默认值
+type RetryPolicy struct {
+	// 最大重试次数, 不含首次请求
+	MaxRetries int
+	// 首次重试退避时间 默认50ms
+	Backoff time.Duration
+	// 最大退避时间 默认1s
+	MaxBackoff time.Duration
+	// 退避倍数 默认2
+	Multiplier float64
+	// 退避抖动比例 0~1 默认0.2
+	Jitter float64
+	// 可重试的应答状态码 默认429/502/503/504
+	StatusCodes []int
+	// 可重试的请求方法 默认GET/HEAD/OPTIONS/PUT/DELETE/TRACE
+	// 带Idempotency-Key的请求也可以重试
+	Methods []string
+	// 包含首次请求在内的最长总耗时, 同时受ctx deadline限制, 0不限制
+	MaxElapsed time.Duration
+	// 重试预算: 10s窗口内重试数不超过 BudgetMinRetries + BudgetRatio*请求数
+	// BudgetRatio 默认0.2, 负数不限制
+	BudgetRatio float64
+	// BudgetMinRetries 默认10
+	BudgetMinRetries int
+}
+
+func (p *RetryPolicy) withDefaults() *RetryPolicy {
+	np := *p
+	if np.Backoff <= 0 {
+		np.Backoff = 50 * time.Millisecond
+	}
+	if np.MaxBackoff < np.Backoff {
+		np.MaxBackoff = time.Second
+		if np.MaxBackoff < np.Backoff {
+			np.MaxBackoff = np.Backoff
+		}
+	}
+	if np.Multiplier < 1 {
+		np.Multiplier = 2
+	}
+	if np.Jitter <= 0 || np.Jitter > 1 {
+		np.Jitter = 0.2
+	}
+	if len(np.StatusCodes) == 0 {
+		np.StatusCodes = []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		}
+	}
+	if len(np.Methods) == 0 {
+		np.Methods = []string{
+			http.MethodGet,
+			http.MethodHead,
+			http.MethodOptions,
+			http.MethodPut,
+			http.MethodDelete,
+			http.MethodTrace,
+		}
+	}
+	if np.BudgetRatio == 0 {
+		np.BudgetRatio = 0.2
+	}
+	if np.BudgetMinRetries <= 0 {
+		np.BudgetMinRetries = 10
+	}
+	return &np
+}
+
+func (p *RetryPolicy) allowMethod(method string) bool {
+	for _, m := range p.Methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *RetryPolicy) retryableStatus(code int) bool {
+	for _, c := range p.StatusCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff 第att次重试的退避时间, att从1开始
+func (p *RetryPolicy) backoff(att int) time.Duration {
+	d := float64(p.Backoff) * math.Pow(p.Multiplier, float64(att-1))
+	if d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	d += d * p.Jitter * (rand.Float64()*2 - 1)
+	return time.Duration(d)
+}
+
+// retryBudget 按秒分桶统计窗口内的请求数和重试数, 防止重试风暴
+type retryBudget struct {
+	ratio float64
+	min   int
+
+	mu      sync.Mutex
+	buckets [budgetBuckets]struct {
+		sec      int64
+		requests int
+		retries  int
+	}
+}
+
+func (b *retryBudget) current(now time.Time) int {
+	sec := now.Unix()
+	idx := int(sec % budgetBuckets)
+	if b.buckets[idx].sec != sec {
+		b.buckets[idx].sec = sec
+		b.buckets[idx].requests = 0
+		b.buckets[idx].retries = 0
+	}
+	return idx
+}
+
+func (b *retryBudget) request() {
+	b.mu.Lock()
+	b.buckets[b.current(time.Now())].requests++
+	b.mu.Unlock()
+}
+
+// withdraw 预算足够时记录一次重试并返回true
+func (b *retryBudget) withdraw() bool {
+	if b.ratio < 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	idx := b.current(now)
+	var requests, retries int
+	for _, bk := range b.buckets {
+		if now.Unix()-bk.sec < budgetBuckets {
+			requests += bk.requests
+			retries += bk.retries
+		}
+	}
+	if float64(retries+1) > float64(b.min)+b.ratio*float64(requests) {
+		return false
+	}
+	b.buckets[idx].retries++
+	return true
+}
+
+// retryAfter 解析Retry-After, 支持秒数和HTTP-date
+func retryAfter(resp *http.Response) time.Duration {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if sec, err := strconv.Atoi(v); err == nil && sec > 0 {
+		return time.Duration(sec) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// drain 丢弃并关闭应答body, 连接可以复用
+func drain(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, drainLimit))
+	_ = resp.Body.Close()
+}
+
+// retryInvoke 按重试策略调用hedgeInvoke, 拦截器链只执行一次, 每次请求记录为span事件
+func retryInvoke(ctx context.Context, c *Client, req *http.Request) (*http.Response, error) {
+	p := c.retryPolicy
+	if p == nil {
+		return hedgeInvoke(ctx, c, req)
+	}
+	c.retryBudget.request()
+	retryable := p.allowMethod(req.Method) || gCtx.GetUberHttpIdempotencyHeader(req.Header) != ""
+	span := opentracing.SpanFromContext(ctx)
+	start := time.Now()
+	for att := 0; ; att++ {
+		attReq := req
+		if att > 0 {
+			attReq = req.Clone(req.Context())
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				attReq.Body = body
+			}
+		}
+		attStart := time.Now()
+		resp, err := hedgeInvoke(ctx, c, attReq)
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else if p.retryableStatus(resp.StatusCode) {
+			reason = strconv.Itoa(resp.StatusCode)
+		}
+		if span != nil {
+			fields := []otlog.Field{
+				otlog.String("event", "http.attempt"),
+				otlog.Int("attempt", att),
+				otlog.String("duration", time.Since(attStart).String()),
+			}
+			if err != nil {
+				fields = append(fields, otlog.Error(err))
+			} else {
+				fields = append(fields, otlog.Int("status", resp.StatusCode))
+			}
+			span.LogFields(fields...)
+		}
+		if reason == "" || !retryable || att >= p.MaxRetries || ctx.Err() != nil {
+			return resp, err
+		}
+		wait := p.backoff(att + 1)
+		if err == nil {
+			if ra := retryAfter(resp); ra > wait {
+				wait = ra
+			}
+		}
+		// 等待后超过ctx deadline或最长总耗时时不再重试
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+			return resp, err
+		}
+		if p.MaxElapsed > 0 && time.Since(start)+wait > p.MaxElapsed {
+			return resp, err
+		}
+		if !c.retryBudget.withdraw() {
+			clientRetries.WithLabelValues(c.name, retryResultBudget).Inc()
+			return resp, err
+		}
+		clientRetries.WithLabelValues(c.name, retryResultRetried).Inc()
+		if resp != nil {
+			drain(resp)
+		}
+		if span != nil {
+			span.LogFields(
+				otlog.String("event", "http.retry"),
+				otlog.Int("attempt", att+1),
+				otlog.String("reason", reason),
+				otlog.String("backoff", wait.String()),
+			)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package client
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+
+	"github.com/wangshanqi84-gif/sagittarius/cores/http/client/balancer"
+	"github.com/wangshanqi84-gif/sagittarius/cores/registry"
+)
+
+const (
+	// repickTimes 选中已请求过的节点时重新选择的次数
+	repickTimes = 3
+)
+
+type triedBuilder struct {
+	inner balancer.Builder
+}
+
+func (b *triedBuilder) Build() balancer.Balancer {
+	return &triedBalancer{inner: b.inner.Build()}
+}
+
+// triedBalancer 重试和对冲时优先选择同一请求未请求过的节点
+type triedBalancer struct {
+	inner balancer.Balancer
+	nodes atomic.Pointer[[]*registry.Service]
+}
+
+func (b *triedBalancer) Pick(ctx context.Context) (*registry.Service, balancer.DoneFunc, error) {
+	tried := triedFromContext(ctx)
+	node, done, err := b.inner.Pick(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := 0; i < repickTimes && tried.has(balancer.Key(node)); i++ {
+		if alt := b.pickOther(tried); alt != nil {
+			if done != nil {
+				done(balancer.DoneInfo{})
+			}
+			node, done = alt, nil
+			break
+		}
+		if done != nil {
+			done(balancer.DoneInfo{})
+		}
+		if node, done, err = b.inner.Pick(ctx); err != nil {
+			return nil, nil, err
+		}
+	}
+	tried.add(balancer.Key(node))
+	return node, done, nil
+}
+
+// pickOther 随机选取未请求过的节点, 没有时返回nil
+func (b *triedBalancer) pickOther(tried *triedNodes) *registry.Service {
+	nodes := b.nodes.Load()
+	if nodes == nil {
+		return nil
+	}
+	var candidates []*registry.Service
+	for _, svc := range *nodes {
+		if !tried.has(balancer.Key(svc)) {
+			candidates = append(candidates, svc)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	return candidates[rand.Intn(len(candidates))]
+}
+
+func (b *triedBalancer) Update(ctx context.Context, service []*registry.Service) {
+	b.nodes.Store(&service)
+	b.inner.Update(ctx, service)
+}
+
+type triedKey struct{}
+
+// triedNodes 同一请求各次重试已请求过的节点
+type triedNodes struct {
+	mu   sync.Mutex
+	keys map[string]struct{}
+}
+
+func newTriedContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, triedKey{}, &triedNodes{keys: make(map[string]struct{})})
+}
+
+func triedFromContext(ctx context.Context) *triedNodes {
+	t, _ := ctx.Value(triedKey{}).(*triedNodes)
+	return t
+}
+
+func (t *triedNodes) has(key string) bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, has := t.keys[key]
+	return has
+}
+
+func (t *triedNodes) add(key string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.keys[key] = struct{}{}
+	t.mu.Unlock()
+}
@@ -0,0 +1,79 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+var ErrCiphertextTooShort = errors.New("ciphertext too short")
+
+// AESGCM 对称加密, 密文格式为base64(nonce+ciphertext)
+type AESGCM struct {
+	aead cipher.AEAD
+}
+
+// NewAESGCM key长度必须为16/24/32字节, 分别对应AES-128/192/256
+func NewAESGCM(key []byte) (*AESGCM, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return &AESGCM{aead: aead}, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.WithMessage(err, "| aes.NewCipher")
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.WithMessage(err, "| cipher.NewGCM")
+	}
+	return aead, nil
+}
+
+func (a *AESGCM) Encrypt(plain string) (string, error) {
+	bs, err := sealGCM(a.aead, []byte(plain))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(bs), nil
+}
+
+func (a *AESGCM) Decrypt(cipherText string) (string, error) {
+	bs, err := base64.StdEncoding.DecodeString(cipherText)
+	if err != nil {
+		return "", errors.WithMessage(err, "| base64.DecodeString")
+	}
+	plain, err := openGCM(a.aead, bs)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+func sealGCM(aead cipher.AEAD, plain []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plain)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, errors.WithMessage(err, "| rand.Read")
+	}
+	return aead.Seal(nonce, nonce, plain, nil), nil
+}
+
+func openGCM(aead cipher.AEAD, data []byte) ([]byte, error) {
+	if len(data) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrCiphertextTooShort
+	}
+	nonce, data := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, data, nil)
+	if err != nil {
+		return nil, errors.WithMessage(err, "| aead.Open")
+	}
+	return plain, nil
+}
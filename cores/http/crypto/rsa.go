@@ -0,0 +1,135 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	hybridKeySize = 32
+)
+
+var (
+	ErrPublicKeyMissing  = errors.New("rsa public key missing")
+	ErrPrivateKeyMissing = errors.New("rsa private key missing")
+	ErrInvalidPEM        = errors.New("invalid pem data")
+)
+
+// RSAAES RSA+AES混合加密
+// 每次加密随机生成AES-256密钥, 使用对端公钥RSA-OAEP(SHA-256)加密密钥, AES-GCM加密数据
+// 密文格式为base64(2字节密钥长度+加密密钥+nonce+ciphertext)
+// 服务端配置客户端公钥和服务端私钥, 客户端配置服务端公钥和客户端私钥
+type RSAAES struct {
+	publicKey  *rsa.PublicKey
+	privateKey *rsa.PrivateKey
+}
+
+// NewRSAAES publicKey用于加密, privateKey用于解密, 单向使用时另一个可为nil
+func NewRSAAES(publicKey *rsa.PublicKey, privateKey *rsa.PrivateKey) *RSAAES {
+	return &RSAAES{
+		publicKey:  publicKey,
+		privateKey: privateKey,
+	}
+}
+
+func (r *RSAAES) Encrypt(plain string) (string, error) {
+	if r.publicKey == nil {
+		return "", ErrPublicKeyMissing
+	}
+	key := make([]byte, hybridKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", errors.WithMessage(err, "| rand.Read")
+	}
+	encKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, r.publicKey, key, nil)
+	if err != nil {
+		return "", errors.WithMessage(err, "| rsa.EncryptOAEP")
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := sealGCM(aead, []byte(plain))
+	if err != nil {
+		return "", err
+	}
+	bs := make([]byte, 2, 2+len(encKey)+len(sealed))
+	binary.BigEndian.PutUint16(bs, uint16(len(encKey)))
+	bs = append(bs, encKey...)
+	bs = append(bs, sealed...)
+	return base64.StdEncoding.EncodeToString(bs), nil
+}
+
+func (r *RSAAES) Decrypt(cipherText string) (string, error) {
+	if r.privateKey == nil {
+		return "", ErrPrivateKeyMissing
+	}
+	bs, err := base64.StdEncoding.DecodeString(cipherText)
+	if err != nil {
+		return "", errors.WithMessage(err, "| base64.DecodeString")
+	}
+	if len(bs) < 2 {
+		return "", ErrCiphertextTooShort
+	}
+	keyLen := int(binary.BigEndian.Uint16(bs))
+	if len(bs) < 2+keyLen {
+		return "", ErrCiphertextTooShort
+	}
+	key, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, r.privateKey, bs[2:2+keyLen], nil)
+	if err != nil {
+		return "", errors.WithMessage(err, "| rsa.DecryptOAEP")
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	plain, err := openGCM(aead, bs[2+keyLen:])
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// ParseRSAPublicKey 解析PEM格式公钥, 支持PKIX和PKCS1
+func ParseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidPEM
+	}
+	if pub, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		if rsaPub, ok := pub.(*rsa.PublicKey); ok {
+			return rsaPub, nil
+		}
+		return nil, errors.New("not rsa public key")
+	}
+	pub, err := x509.ParsePKCS1PublicKey(block.Bytes)
+	if err != nil {
+		return nil, errors.WithMessage(err, "| x509.ParsePKCS1PublicKey")
+	}
+	return pub, nil
+}
+
+// ParseRSAPrivateKey 解析PEM格式私钥, 支持PKCS8和PKCS1
+func ParseRSAPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidPEM
+	}
+	if priv, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		if rsaPriv, ok := priv.(*rsa.PrivateKey); ok {
+			return rsaPriv, nil
+		}
+		return nil, errors.New("not rsa private key")
+	}
+	priv, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.WithMessage(err, "| x509.ParsePKCS1PrivateKey")
+	}
+	return priv, nil
+}
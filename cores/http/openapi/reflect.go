@@ -0,0 +1,269 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	bytesType      = reflect.TypeOf([]byte{})
+
+	invalidNameChar = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+	// patterns 与server中同名校验规则对应
+	patterns = map[string]string{
+		"numeric":  `^[-+]?[0-9]*\.?[0-9]+([eE][-+]?[0-9]+)?$`,
+		"alpha":    `^[a-zA-Z]+$`,
+		"alphanum": `^[a-zA-Z0-9]+$`,
+	}
+	formats = map[string]string{
+		"email": "email",
+		"url":   "uri",
+	}
+)
+
+// Field 结构体字段, 用于生成query/path参数
+type Field struct {
+	Name     string
+	Required bool
+	Schema   *Schema
+}
+
+// Reflector 根据json和validate标签生成schema, 具名结构体放入Schemas并通过$ref引用
+type Reflector struct {
+	Schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func NewReflector() *Reflector {
+	return &Reflector{
+		Schemas: make(map[string]*Schema),
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+// Schema 生成类型的schema
+func (r *Reflector) Schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawMessageType:
+		return &Schema{}
+	case bytesType:
+		return &Schema{Type: "string", Format: "byte"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: r.Schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: r.Schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return r.structSchema(t)
+		}
+		name, has := r.names[t]
+		if !has {
+			name = r.register(t)
+			r.Schemas[name] = r.structSchema(t)
+		}
+		return RefSchema(name)
+	}
+	// interface{}等任意类型
+	return &Schema{}
+}
+
+// register 分配components中的名称, 重名时使用包名前缀
+func (r *Reflector) register(t reflect.Type) string {
+	name := invalidNameChar.ReplaceAllString(t.Name(), "_")
+	if _, has := r.Schemas[name]; has {
+		pkg := t.PkgPath()
+		if idx := strings.LastIndex(pkg, "/"); idx >= 0 {
+			pkg = pkg[idx+1:]
+		}
+		base := invalidNameChar.ReplaceAllString(pkg, "_") + "." + name
+		name = base
+		for i := 2; ; i++ {
+			if _, has = r.Schemas[name]; !has {
+				break
+			}
+			name = base + strconv.Itoa(i)
+		}
+	}
+	r.names[t] = name
+	// 先占位, 自引用类型通过$ref解决
+	r.Schemas[name] = &Schema{}
+	return name
+}
+
+func (r *Reflector) structSchema(t reflect.Type) *Schema {
+	s := &Schema{
+		Type:       "object",
+		Properties: make(map[string]*Schema),
+	}
+	for _, f := range r.Fields(t) {
+		s.Properties[f.Name] = f.Schema
+		if f.Required {
+			s.Required = append(s.Required, f.Name)
+		}
+	}
+	return s
+}
+
+// Fields 按encoding/json规则展开结构体字段
+func (r *Reflector) Fields(t reflect.Type) []Field {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	var fields []Field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		ft := sf.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		// 匿名结构体字段展开
+		if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			fields = append(fields, r.Fields(ft)...)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		schema := r.Schema(sf.Type)
+		required := applyValidate(schema, ft, sf.Tag.Get("validate"))
+		// 3.1中$ref允许同级的description
+		schema.Description = sf.Tag.Get("description")
+		fields = append(fields, Field{
+			Name:     name,
+			Required: required,
+			Schema:   schema,
+		})
+	}
+	return fields
+}
+
+// applyValidate 将validate规则转换为schema约束, 返回是否必填
+func applyValidate(s *Schema, t reflect.Type, tag string) bool {
+	if tag == "" || tag == "-" || s.Ref != "" {
+		return strings.Contains(","+tag+",", ",required,")
+	}
+	required := false
+	for _, item := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(item), "=")
+		switch name {
+		case "required":
+			required = true
+		case "min", "gte":
+			setBound(s, t, param, 0, true)
+		case "max", "lte":
+			setBound(s, t, param, 0, false)
+		case "len":
+			setBound(s, t, param, 0, true)
+			setBound(s, t, param, 0, false)
+		case "gt":
+			setBound(s, t, param, 1, true)
+		case "lt":
+			setBound(s, t, param, -1, false)
+		case "oneof":
+			for _, v := range strings.Fields(param) {
+				s.Enum = append(s.Enum, enumValue(t, v))
+			}
+		default:
+			if p, has := patterns[name]; has && s.Type == "string" {
+				s.Pattern = p
+			}
+			if f, has := formats[name]; has && s.Type == "string" {
+				s.Format = f
+			}
+		}
+	}
+	return required
+}
+
+// setBound 字符串/数组约束长度, 数字约束取值, delta用于gt/lt的开区间
+func setBound(s *Schema, t reflect.Type, param string, delta int, lower bool) {
+	switch t.Kind() {
+	case reflect.Map:
+		return
+	case reflect.String, reflect.Slice, reflect.Array:
+		n, err := strconv.Atoi(param)
+		if err != nil {
+			return
+		}
+		n += delta
+		if t.Kind() == reflect.String {
+			if lower {
+				s.MinLength = &n
+			} else {
+				s.MaxLength = &n
+			}
+			return
+		}
+		if lower {
+			s.MinItems = &n
+		} else {
+			s.MaxItems = &n
+		}
+	default:
+		f, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return
+		}
+		switch {
+		case delta > 0:
+			s.ExclusiveMinimum = &f
+		case delta < 0:
+			s.ExclusiveMaximum = &f
+		case lower:
+			s.Minimum = &f
+		default:
+			s.Maximum = &f
+		}
+	}
+}
+
+func enumValue(t reflect.Type, v string) interface{} {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n, err := strconv.ParseUint(v, 10, 64); err == nil {
+			return n
+		}
+	}
+	return v
+}
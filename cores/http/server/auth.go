@@ -0,0 +1,65 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/wangshanqi84-gif/sagittarius/cores/auth"
+
+	"github.com/pkg/errors"
+)
+
+// AuthHandler 认证请求, 通过后principal写入Ctx(), 可用auth.FromContext获取
+// 跨域预检请求直接放行, 由CORSHandler处理
+func AuthHandler(a auth.Authenticator) core {
+	return func(c *Context) {
+		if c.Request().Method == http.MethodOptions && c.Request().Header.Get("Access-Control-Request-Method") != "" {
+			c.Next()
+			return
+		}
+		p, err := a.Authenticate(c.Ctx(), &auth.Request{
+			Method:    c.Request().Method,
+			Path:      c.Request().URL.Path,
+			Query:     c.Request().URL.RawQuery,
+			Header:    c.Request().Header.Get,
+			Body:      c.reqBody,
+			Streaming: c.streamBody,
+		})
+		if err != nil {
+			if errors.Is(err, auth.ErrNoCredentials) {
+				c.Writer().Header().Set("WWW-Authenticate", "Bearer")
+			}
+			_ = c.HttpError(http.StatusUnauthorized, "unauthorized!")
+			c.Abort()
+			return
+		}
+		c.ctx = auth.NewContext(c.ctx, p)
+		c.Next()
+	}
+}
+
+// RequireScopes 要求principal拥有全部scope, 需放在AuthHandler之后
+func RequireScopes(scopes ...string) core {
+	return func(c *Context) {
+		if c.Request().Method == http.MethodOptions && c.Request().Header.Get("Access-Control-Request-Method") != "" {
+			c.Next()
+			return
+		}
+		p, ok := auth.FromContext(c.Ctx())
+		if !ok {
+			_ = c.HttpError(http.StatusUnauthorized, "unauthorized!")
+			c.Abort()
+			return
+		}
+		if !p.HasScopes(scopes...) {
+			_ = c.HttpError(http.StatusForbidden, "forbidden!")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// Principal 认证通过的调用方
+func (c *Context) Principal() (*auth.Principal, bool) {
+	return auth.FromContext(c.ctx)
+}
@@ -3,17 +3,45 @@ package server
 import (
 	"encoding/json"
 	"encoding/xml"
+	"mime"
 	"net/url"
+	"strings"
+	"sync"
 
 	"github.com/go-playground/form/v4"
+	"github.com/pkg/errors"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	MIMEJSON      = "application/json"
+	MIMEXML       = "application/xml"
+	MIMETextXML   = "text/xml"
+	MIMEForm      = "application/x-www-form-urlencoded"
+	MIMEMultipart = "multipart/form-data"
+	MIMEProtobuf  = "application/x-protobuf"
+	MIMEMsgpack   = "application/msgpack"
+)
+
+var (
+	ErrNotProtoMessage = errors.New("value is not proto.Message")
 )
 
 var (
-	_binders = map[string]IBinder{
-		"application/x-www-form-urlencoded": newFormBinder(),
-		"application/json":                  newJsonBinder(),
-		"application/xml":                   newXmlBinder(),
-		"query":                             newQueryBinder(),
+	_queryBinder = newQueryBinder()
+
+	bindersMu sync.RWMutex
+	_binders  = map[string]IBinder{
+		MIMEForm:                newFormBinder(),
+		MIMEMultipart:           newMultipartBinder(),
+		MIMEJSON:                newJsonBinder(),
+		MIMEXML:                 newXmlBinder(),
+		MIMETextXML:             newXmlBinder(),
+		MIMEProtobuf:            newProtoBinder(),
+		"application/protobuf":  newProtoBinder(),
+		MIMEMsgpack:             newMsgpackBinder(),
+		"application/x-msgpack": newMsgpackBinder(),
 	}
 )
 
@@ -21,6 +49,41 @@ type IBinder interface {
 	Unmarshal(c *Context, v interface{}) error
 }
 
+// RegisterBinder 注册请求body解析器, mediaType不含参数, 如"application/json"
+func RegisterBinder(mediaType string, binder IBinder) {
+	bindersMu.Lock()
+	defer bindersMu.Unlock()
+
+	_binders[strings.ToLower(mediaType)] = binder
+}
+
+// Binder 根据Content-Type查找解析器, "+json"/"+xml"后缀的类型使用对应的解析器
+func Binder(contentType string) (IBinder, bool) {
+	mediaType := parseMediaType(contentType)
+
+	bindersMu.RLock()
+	defer bindersMu.RUnlock()
+
+	if binder, has := _binders[mediaType]; has {
+		return binder, true
+	}
+	if strings.HasSuffix(mediaType, "+json") {
+		return _binders[MIMEJSON], true
+	}
+	if strings.HasSuffix(mediaType, "+xml") {
+		return _binders[MIMEXML], true
+	}
+	return nil, false
+}
+
+func parseMediaType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.TrimSpace(strings.Split(contentType, ";")[0])
+	}
+	return strings.ToLower(mediaType)
+}
+
 type FormBinder struct {
 	decoder *form.Decoder
 }
@@ -44,6 +107,27 @@ func (fb *FormBinder) Unmarshal(c *Context, v interface{}) error {
 	return fb.decoder.Decode(v, vs)
 }
 
+type MultipartBinder struct {
+	decoder *form.Decoder
+}
+
+func newMultipartBinder() *MultipartBinder {
+	d := form.NewDecoder()
+	d.SetTagName("json")
+	return &MultipartBinder{
+		decoder: d,
+	}
+}
+
+// Unmarshal 只解析表单字段, 文件通过Context.FormFile获取
+func (mb *MultipartBinder) Unmarshal(c *Context, v interface{}) error {
+	mf, err := c.MultipartForm()
+	if err != nil {
+		return err
+	}
+	return mb.decoder.Decode(v, mf.Value)
+}
+
 type QueryBinder struct {
 	decoder *form.Decoder
 }
@@ -79,3 +163,27 @@ func newXmlBinder() *XmlBinder {
 func (xb *XmlBinder) Unmarshal(c *Context, v interface{}) error {
 	return xml.Unmarshal(c.reqBody, v)
 }
+
+type ProtoBinder struct{}
+
+func newProtoBinder() *ProtoBinder {
+	return &ProtoBinder{}
+}
+
+func (pb *ProtoBinder) Unmarshal(c *Context, v interface{}) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return ErrNotProtoMessage
+	}
+	return proto.Unmarshal(c.reqBody, msg)
+}
+
+type MsgpackBinder struct{}
+
+func newMsgpackBinder() *MsgpackBinder {
+	return &MsgpackBinder{}
+}
+
+func (mb *MsgpackBinder) Unmarshal(c *Context, v interface{}) error {
+	return msgpack.Unmarshal(c.reqBody, v)
+}
@@ -0,0 +1,287 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+	EncodingBrotli  = "br"
+	EncodingZstd    = "zstd"
+
+	compressMinLength = 1024
+)
+
+var (
+	// defaultEncodings 权重相同时的优先顺序
+	defaultEncodings = []string{EncodingBrotli, EncodingZstd, EncodingGzip, EncodingDeflate}
+
+	defaultCompressTypes = []string{
+		"text/",
+		"application/json",
+		"application/xml",
+		"application/javascript",
+		"application/x-www-form-urlencoded",
+		"image/svg+xml",
+		"+json",
+		"+xml",
+	}
+
+	compressorPools = map[string]*sync.Pool{
+		EncodingGzip: {New: func() interface{} {
+			w, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+			return w
+		}},
+		// HTTP的deflate为zlib格式(RFC 9110 8.4.1.2), 不是裸DEFLATE
+		EncodingDeflate: {New: func() interface{} {
+			w, _ := zlib.NewWriterLevel(nil, zlib.DefaultCompression)
+			return w
+		}},
+		EncodingBrotli: {New: func() interface{} {
+			return brotli.NewWriterLevel(nil, brotli.DefaultCompression)
+		}},
+		EncodingZstd: {New: func() interface{} {
+			w, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+			return w
+		}},
+	}
+)
+
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// CompressOptions 应答压缩配置
+type CompressOptions struct {
+	// Encodings 支持的编码及优先顺序, 为空时使用br, zstd, gzip, deflate
+	Encodings []string
+	// MinLength 小于该长度的应答不压缩, 为0时使用1024, 流式输出(Flush)不受限制
+	MinLength int
+	// ContentTypes Content-Type前缀白名单, "+json"形式按后缀匹配, 为空时使用常见文本类型
+	ContentTypes []string
+}
+
+// CompressHandler 根据Accept-Encoding压缩应答
+func CompressHandler(opts CompressOptions) core {
+	encodings := opts.Encodings
+	if len(encodings) == 0 {
+		encodings = defaultEncodings
+	}
+	minLength := opts.MinLength
+	if minLength <= 0 {
+		minLength = compressMinLength
+	}
+	types := opts.ContentTypes
+	if len(types) == 0 {
+		types = defaultCompressTypes
+	}
+	return func(c *Context) {
+		if c.Request().Method == http.MethodHead {
+			c.Next()
+			return
+		}
+		encoding := negotiateEncoding(c.Request().Header.Get("Accept-Encoding"), encodings)
+		if encoding == "" {
+			c.Next()
+			return
+		}
+		cw := &compressWriter{
+			ResponseWriter: c.w,
+			encoding:       encoding,
+			minLength:      minLength,
+			types:          types,
+			status:         http.StatusOK,
+		}
+		c.w = cw
+		defer func() {
+			_ = cw.close()
+			c.w = cw.ResponseWriter
+		}()
+		c.Next()
+	}
+}
+
+// negotiateEncoding 选择权重最高的编码, 权重相同时按encodings顺序
+func negotiateEncoding(header string, encodings []string) string {
+	if header == "" {
+		return ""
+	}
+	accepts := acceptEncodings(header)
+	var (
+		best  string
+		bestQ float64
+	)
+	for _, enc := range encodings {
+		if _, has := compressorPools[enc]; !has {
+			continue
+		}
+		q, has := accepts[enc]
+		if !has {
+			q, has = accepts["*"]
+		}
+		if has && q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best
+}
+
+type compressWriter struct {
+	http.ResponseWriter
+	encoding  string
+	minLength int
+	types     []string
+
+	status      int
+	wroteHeader bool
+	decided     bool
+	buf         []byte
+	cw          compressor
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	// 1xx直接发送
+	if code >= 100 && code < 200 {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.wroteHeader = true
+	w.status = code
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) < w.minLength {
+			return len(b), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if w.cw != nil {
+		return w.cw.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// decide 确定是否压缩并发送header和缓存的数据, sizeOK表示长度满足要求
+func (w *compressWriter) decide(sizeOK bool) error {
+	w.decided = true
+	header := w.Header()
+	if header.Get("Content-Type") == "" && len(w.buf) > 0 {
+		header.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	if w.compressible() {
+		header.Add("Vary", "Accept-Encoding")
+		if sizeOK {
+			header.Set("Content-Encoding", w.encoding)
+			header.Del("Content-Length")
+			w.cw = compressorPools[w.encoding].Get().(compressor)
+			w.cw.Reset(w.ResponseWriter)
+		}
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.cw != nil {
+		_, err = w.cw.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+func (w *compressWriter) compressible() bool {
+	switch {
+	case w.status < http.StatusOK,
+		w.status == http.StatusNoContent,
+		w.status == http.StatusPartialContent,
+		w.status == http.StatusNotModified:
+		return false
+	}
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+	mediaType := parseMediaType(header.Get("Content-Type"))
+	for _, t := range w.types {
+		if strings.HasPrefix(t, "+") {
+			if strings.HasSuffix(mediaType, t) {
+				return true
+			}
+			continue
+		}
+		if strings.HasPrefix(mediaType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *compressWriter) Flush() {
+	_ = w.FlushError()
+}
+
+// FlushError 流式输出(如SSE)时立即确定压缩并刷新
+func (w *compressWriter) FlushError() error {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.decided {
+		if err := w.decide(true); err != nil {
+			return err
+		}
+	}
+	if w.cw != nil {
+		if err := w.cw.Flush(); err != nil {
+			return err
+		}
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *compressWriter) close() error {
+	if !w.decided {
+		if !w.wroteHeader && len(w.buf) == 0 {
+			return nil
+		}
+		if err := w.decide(len(w.buf) >= w.minLength); err != nil {
+			return err
+		}
+	}
+	if w.cw == nil {
+		return nil
+	}
+	err := w.cw.Close()
+	w.cw.Reset(nil)
+	compressorPools[w.encoding].Put(w.cw)
+	w.cw = nil
+	return err
+}
@@ -4,7 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"encoding/xml"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
 	"net/http"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
@@ -24,11 +30,15 @@ type Context struct {
 	r         *http.Request
 	w         http.ResponseWriter
 	pathParam map[string]string
+	fullPath  string
 
 	reqBody        []byte
+	streamBody     bool
 	reqData        interface{}
 	logWithoutResp bool
 	respData       interface{}
+	bizStatus      int
+	stream         *SSEStream
 
 	cores []core
 	index int8
@@ -58,9 +68,13 @@ func (c *Context) reset() *Context {
 	c.srv = nil
 	c.ctx = context.TODO()
 	c.respData = nil
+	c.bizStatus = 0
 	c.reqData = nil
 	c.reqBody = nil
+	c.streamBody = false
 	c.logWithoutResp = false
+	c.stream = nil
+	c.fullPath = ""
 	c.pathParam = make(map[string]string)
 	return c
 }
@@ -92,6 +106,39 @@ func (c *Context) GetPathParamInt64(key string) int64 {
 	}
 }
 
+// FullPath 匹配到的路由模板, 如"/user/{id}"
+func (c *Context) FullPath() string {
+	return c.fullPath
+}
+
+// ClientIP 客户端ip, 对端不是可信代理(TrustedProxies)时直接使用RemoteAddr
+// 对端可信时从右向左取X-Forwarded-For中第一个非可信代理的地址, 没有时使用X-Real-IP
+func (c *Context) ClientIP() string {
+	remote, _, err := net.SplitHostPort(c.r.RemoteAddr)
+	if err != nil {
+		remote = c.r.RemoteAddr
+	}
+	if c.srv == nil || !c.srv.trusted(remote) {
+		return remote
+	}
+	if xff := c.r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		ips := strings.Split(strings.Join(xff, ","), ",")
+		for idx := len(ips) - 1; idx >= 0; idx-- {
+			ip := strings.TrimSpace(ips[idx])
+			if ip == "" {
+				continue
+			}
+			if idx == 0 || !c.srv.trusted(ip) {
+				return ip
+			}
+		}
+	}
+	if ip := strings.TrimSpace(c.r.Header.Get("X-Real-IP")); ip != "" {
+		return ip
+	}
+	return remote
+}
+
 func (c *Context) Ctx() context.Context {
 	return c.ctx
 }
@@ -130,6 +177,64 @@ func (c *Context) Request() *http.Request {
 	return c.r
 }
 
+// MultipartReader 流式读取multipart body, 配合Group.StreamBody使用可避免整体读入内存
+func (c *Context) MultipartReader() (*multipart.Reader, error) {
+	return c.r.MultipartReader()
+}
+
+// MultipartForm 解析multipart表单, 超过MultipartMemory的文件内容写入临时文件
+// 未配置Crypto时body不预读, Body()为空; 配置Crypto时从解密后的内容解析
+// 临时文件在请求结束后由net/http自动清理
+func (c *Context) MultipartForm() (*multipart.Form, error) {
+	if c.r.MultipartForm == nil {
+		memory := int64(multipartMemory)
+		if c.srv != nil {
+			memory = c.srv.multipartMemory
+		}
+		if err := c.r.ParseMultipartForm(memory); err != nil {
+			return nil, err
+		}
+	}
+	return c.r.MultipartForm, nil
+}
+
+func (c *Context) FormFile(name string) (multipart.File, *multipart.FileHeader, error) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, nil, err
+	}
+	fhs := form.File[name]
+	if len(fhs) == 0 {
+		return nil, nil, http.ErrMissingFile
+	}
+	f, err := fhs[0].Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, fhs[0], nil
+}
+
+// SaveUploadedFile 保存上传文件到dst
+func (c *Context) SaveUploadedFile(fh *multipart.FileHeader, dst string) error {
+	src, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if err = os.MkdirAll(filepath.Dir(dst), 0750); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, src)
+	return err
+}
+
 func (c *Context) LogWithoutResp() {
 	c.logWithoutResp = true
 }
@@ -174,35 +279,32 @@ func (c *Context) ParseHeader(data interface{}) {
 
 func (c *Context) Bind(v interface{}) error {
 	// 解析query
-	queryBinder := _binders["query"]
-	if queryBinder != nil {
-		if v != nil {
-			err := queryBinder.Unmarshal(c, v)
-			if err != nil {
-				return err
-			}
+	if v != nil {
+		err := _queryBinder.Unmarshal(c, v)
+		if err != nil {
+			return err
 		}
 	}
-	// 解析body
-	if len(c.reqBody) > 0 {
-		accept := c.Request().Header.Get("Content-Type")
-		if accept == "" {
+	// 解析body, multipart未预读时从Request().Body解析
+	contentType := c.Request().Header.Get("Content-Type")
+	if len(c.reqBody) > 0 || parseMediaType(contentType) == MIMEMultipart {
+		if contentType == "" {
 			// 默认使用json
-			accept = "application/json"
+			contentType = MIMEJSON
 		}
-		// 这里检查使用哪个binder
-		for k, binder := range _binders {
-			if strings.Contains(accept, k) {
-				err := binder.Unmarshal(c, v)
-				if err != nil {
-					return err
-				}
-				break
+		if binder, has := Binder(contentType); has {
+			err := binder.Unmarshal(c, v)
+			if err != nil {
+				return err
 			}
 		}
 	}
 	if v != nil {
 		c.reqData = v
+		// 按validate标签校验
+		if err := Validate(v); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -220,30 +322,20 @@ func (c *Context) HttpError(code int, message string) error {
 }
 
 func (c *Context) JsonCustom(data interface{}) error {
-	c.w.Header().Add("Content-Type", "application/json")
-	c.w.WriteHeader(http.StatusOK)
-
 	if data == nil {
-		return nil
+		return c.render("application/json", nil)
 	}
 	c.respData = data
 	bs, err := json.Marshal(data)
 	if err != nil {
 		return err
 	}
-	_, err = c.w.Write(bs)
-	if err != nil {
-		return err
-	}
-	return nil
+	return c.render("application/json", bs)
 }
 
 func (c *Context) FormCustom(data interface{}) error {
-	c.w.Header().Add("Content-Type", "application/x-www-form-urlencoded")
-	c.w.WriteHeader(http.StatusOK)
-
 	if data == nil {
-		return nil
+		return c.render("application/x-www-form-urlencoded", nil)
 	}
 	c.respData = data
 	e := form.NewEncoder()
@@ -251,30 +343,19 @@ func (c *Context) FormCustom(data interface{}) error {
 	if err != nil {
 		return err
 	}
-	_, err = c.w.Write([]byte(vs.Encode()))
-	if err != nil {
-		return err
-	}
-	return nil
+	return c.render("application/x-www-form-urlencoded", []byte(vs.Encode()))
 }
 
 func (c *Context) XmlCustom(data interface{}) error {
-	c.w.Header().Add("Content-Type", "application/xml")
-	c.w.WriteHeader(http.StatusOK)
-
 	if data == nil {
-		return nil
+		return c.render("application/xml", nil)
 	}
 	c.respData = data
 	bs, err := xml.Marshal(data)
 	if err != nil {
 		return err
 	}
-	_, err = c.w.Write(bs)
-	if err != nil {
-		return err
-	}
-	return nil
+	return c.render("application/xml", bs)
 }
 
 func (c *Context) CustomBinary(data []byte) error {
@@ -292,144 +373,104 @@ func (c *Context) CustomBinary(data []byte) error {
 	return nil
 }
 
-func (c *Context) JsonOK(data interface{}) error {
-	c.w.Header().Add("Content-Type", "application/json")
-	c.w.WriteHeader(http.StatusOK)
+// Ok 根据Accept选择应答格式
+func (c *Context) Ok(data interface{}) error {
+	c.w.Header().Add("Vary", "Accept")
+	return c.renderOK(negotiate(c.r.Header.Get("Accept")), data)
+}
 
-	body := map[string]interface{}{
-		"status":  0,
-		"message": "success",
-	}
-	if data != nil {
-		body["data"] = data
-	}
-	bs, err := json.Marshal(body)
-	if err != nil {
-		return err
-	}
-	c.buildRespData(http.StatusOK, 0, "", data)
-	_, err = c.w.Write(bs)
-	if err != nil {
-		return err
-	}
-	return nil
+// Err 根据Accept选择应答格式
+func (c *Context) Err(err error) error {
+	c.w.Header().Add("Vary", "Accept")
+	return c.renderErr(negotiate(c.r.Header.Get("Accept")), err)
 }
 
-func (c *Context) FormOK(data interface{}) error {
-	c.w.Header().Add("Content-Type", "application/x-www-form-urlencoded")
-	c.w.WriteHeader(http.StatusOK)
+func (c *Context) JsonOK(data interface{}) error {
+	return c.renderOK(MIMEJSON, data)
+}
 
-	body := map[string]interface{}{
-		"status":  0,
-		"message": "success",
-	}
-	if data != nil {
-		body["data"] = data
-	}
-	e := form.NewEncoder()
-	vs, err := e.Encode(body)
-	if err != nil {
-		return err
-	}
-	c.buildRespData(http.StatusOK, 0, "", data)
-	_, err = c.w.Write([]byte(vs.Encode()))
-	if err != nil {
-		return err
-	}
-	return nil
+func (c *Context) FormOK(data interface{}) error {
+	return c.renderOK(MIMEForm, data)
 }
 
 func (c *Context) XmlOK(data interface{}) error {
-	c.w.Header().Add("Content-Type", "application/xml")
-	c.w.WriteHeader(http.StatusOK)
-
-	body := map[string]interface{}{
-		"status":  0,
-		"message": "success",
-	}
-	if data != nil {
-		body["data"] = data
-	}
-	bs, err := xml.Marshal(body)
-	if err != nil {
-		return err
-	}
-	c.buildRespData(http.StatusOK, 0, "", data)
-	_, err = c.w.Write(bs)
-	if err != nil {
-		return err
-	}
-	return nil
+	return c.renderOK(MIMEXML, data)
 }
 
 func (c *Context) JsonErr(err error) error {
-	c.w.Header().Add("Content-Type", "application/json")
-	c.w.WriteHeader(http.StatusOK)
+	return c.renderErr(MIMEJSON, err)
+}
 
-	ge := gErrors.Cause(err, gCtx.FromLangClientContext(c.ctx))
-	body := map[string]interface{}{
-		"status":  ge.Code(),
-		"message": ge.Message(),
-	}
-	bs, err := json.Marshal(body)
-	if err != nil {
-		return err
+func (c *Context) FormErr(err error) error {
+	return c.renderErr(MIMEForm, err)
+}
+
+func (c *Context) XmlErr(err error) error {
+	return c.renderErr(MIMEXML, err)
+}
+
+func (c *Context) renderOK(mediaType string, data interface{}) error {
+	r, has := Renderer(mediaType)
+	if !has {
+		return fmt.Errorf("renderer %s not found", mediaType)
 	}
-	c.buildRespData(http.StatusOK, ge.Code(), ge.Message(), nil)
-	_, err = c.w.Write(bs)
+	bs, err := r.Marshal(&Response{
+		Status:  0,
+		Message: "success",
+		Data:    data,
+	})
 	if err != nil {
 		return err
 	}
-	return nil
+	c.buildRespData(http.StatusOK, 0, "", data)
+	return c.render(mediaType, bs)
 }
 
-func (c *Context) FormErr(err error) error {
-	c.w.Header().Add("Content-Type", "application/x-www-form-urlencoded")
-	c.w.WriteHeader(http.StatusOK)
-
-	ge := gErrors.Cause(err, gCtx.FromLangClientContext(c.ctx))
-	body := map[string]interface{}{
-		"status":  ge.Code(),
-		"message": ge.Message(),
+func (c *Context) renderErr(mediaType string, err error) error {
+	r, has := Renderer(mediaType)
+	if !has {
+		return fmt.Errorf("renderer %s not found", mediaType)
 	}
-	e := form.NewEncoder()
-	vs, err := e.Encode(body)
+	ge := gErrors.Cause(err, gCtx.FromLangClientContext(c.ctx))
+	bs, err := r.Marshal(&Response{
+		Status:  ge.Code(),
+		Message: ge.Message(),
+		Details: ge.Details(),
+	})
 	if err != nil {
 		return err
 	}
 	c.buildRespData(http.StatusOK, ge.Code(), ge.Message(), nil)
-	_, err = c.w.Write([]byte(vs.Encode()))
-	if err != nil {
-		return err
-	}
-	return nil
+	return c.render(mediaType, bs)
 }
 
-func (c *Context) XmlErr(err error) error {
-	c.w.Header().Add("Content-Type", "application/xml")
+// render 写入200应答, 开启EncryptResponse时使用crypto加密body
+func (c *Context) render(contentType string, body []byte) error {
+	if len(body) > 0 && c.srv != nil && c.srv.crypto != nil && c.srv.encryptResponse {
+		s, err := c.srv.crypto.Encrypt(string(body))
+		if err != nil {
+			return err
+		}
+		body = []byte(s)
+		gCtx.SetUberHttpEncryptHeader(c.w.Header())
+	}
+	c.w.Header().Add("Content-Type", contentType)
 	c.w.WriteHeader(http.StatusOK)
 
-	ge := gErrors.Cause(err, gCtx.FromLangClientContext(c.ctx))
-	body := map[string]interface{}{
-		"status":  ge.Code(),
-		"message": ge.Message(),
-	}
-	bs, err := xml.Marshal(body)
-	if err != nil {
-		return err
-	}
-	c.buildRespData(http.StatusOK, ge.Code(), ge.Message(), nil)
-	_, err = c.w.Write(bs)
-	if err != nil {
-		return err
+	if len(body) == 0 {
+		return nil
 	}
-	return nil
+	_, err := c.w.Write(body)
+	return err
 }
 
 func (c *Context) buildRespData(httpCode int, status int, message string, body interface{}) {
 	data := map[string]interface{}{
 		"httpCode": httpCode,
 	}
+	if httpCode == http.StatusOK {
+		c.bizStatus = status
+	}
 	if httpCode != http.StatusOK {
 		data["message"] = message
 	} else {
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	defaultCORSMethods = []string{
+		http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodHead,
+	}
+)
+
+// CORSOptions 跨域配置
+type CORSOptions struct {
+	// AllowOrigins 允许的Origin, 支持"*"及"https://*.example.com"形式的子域名通配
+	AllowOrigins []string
+	// AllowOriginFunc 自定义Origin校验, 优先于AllowOrigins
+	AllowOriginFunc func(origin string) bool
+	// AllowMethods 为空时使用GET, POST, PUT, PATCH, DELETE, HEAD
+	AllowMethods []string
+	// AllowHeaders 为空时回显Access-Control-Request-Headers
+	AllowHeaders     []string
+	ExposeHeaders    []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+type corsPolicy struct {
+	allowAll         bool
+	origins          map[string]struct{}
+	wildcards        [][2]string
+	originFunc       func(string) bool
+	allowMethods     string
+	allowHeaders     string
+	exposeHeaders    string
+	allowCredentials bool
+	maxAge           string
+}
+
+func newCORSPolicy(opts CORSOptions) *corsPolicy {
+	p := &corsPolicy{
+		origins:          make(map[string]struct{}),
+		originFunc:       opts.AllowOriginFunc,
+		allowHeaders:     strings.Join(opts.AllowHeaders, ", "),
+		exposeHeaders:    strings.Join(opts.ExposeHeaders, ", "),
+		allowCredentials: opts.AllowCredentials,
+	}
+	for _, o := range opts.AllowOrigins {
+		o = strings.ToLower(strings.TrimSpace(o))
+		if o == "*" {
+			p.allowAll = true
+			continue
+		}
+		if idx := strings.Index(o, "*"); idx >= 0 {
+			p.wildcards = append(p.wildcards, [2]string{o[:idx], o[idx+1:]})
+			continue
+		}
+		p.origins[o] = struct{}{}
+	}
+	methods := opts.AllowMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	p.allowMethods = strings.ToUpper(strings.Join(methods, ", "))
+	if opts.MaxAge > 0 {
+		p.maxAge = strconv.FormatInt(int64(opts.MaxAge/time.Second), 10)
+	}
+	return p
+}
+
+func (p *corsPolicy) allowOrigin(origin string) bool {
+	if p.originFunc != nil {
+		return p.originFunc(origin)
+	}
+	if p.allowAll {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if _, has := p.origins[origin]; has {
+		return true
+	}
+	for _, w := range p.wildcards {
+		if len(origin) > len(w[0])+len(w[1]) &&
+			strings.HasPrefix(origin, w[0]) && strings.HasSuffix(origin, w[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// CORSHandler 跨域中间件, 同时处理预检请求和普通请求
+func CORSHandler(opts CORSOptions) core {
+	p := newCORSPolicy(opts)
+	return func(c *Context) {
+		origin := c.Request().Header.Get("Origin")
+		header := c.Writer().Header()
+		header.Add("Vary", "Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+		preflight := c.Request().Method == http.MethodOptions &&
+			c.Request().Header.Get("Access-Control-Request-Method") != ""
+		if !p.allowOrigin(origin) {
+			if preflight {
+				c.Writer().WriteHeader(http.StatusForbidden)
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+		if p.allowAll && !p.allowCredentials && p.originFunc == nil {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if p.allowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+		if !preflight {
+			if p.exposeHeaders != "" {
+				header.Set("Access-Control-Expose-Headers", p.exposeHeaders)
+			}
+			c.Next()
+			return
+		}
+		// 预检请求
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+		header.Set("Access-Control-Allow-Methods", p.allowMethods)
+		if p.allowHeaders != "" {
+			header.Set("Access-Control-Allow-Headers", p.allowHeaders)
+		} else if reqHeaders := c.Request().Header.Get("Access-Control-Request-Headers"); reqHeaders != "" {
+			header.Set("Access-Control-Allow-Headers", reqHeaders)
+		}
+		if p.maxAge != "" {
+			header.Set("Access-Control-Max-Age", p.maxAge)
+		}
+		c.Writer().WriteHeader(http.StatusNoContent)
+		c.Abort()
+	}
+}
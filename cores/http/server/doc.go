@@ -0,0 +1,165 @@
+package server
+
+import (
+	"reflect"
+	"sort"
+
+	gServer "github.com/wangshanqi84-gif/sagittarius/cores/server"
+)
+
+// RouteDoc 路由文档信息, 用于生成OpenAPI
+type RouteDoc struct {
+	Summary     string
+	Description string
+	OperationID string
+	Tags        []string
+	Deprecated  bool
+	// Hidden 不出现在OpenAPI文档中
+	Hidden bool
+	// Consumes 请求body的Content-Type, 默认application/json
+	Consumes []string
+	// Request Bind的参数类型, GET/DELETE/HEAD时作为query参数
+	Request reflect.Type
+	// Response Ok/JsonOK中data的类型
+	Response reflect.Type
+	// RawResponse 为true时Response为完整应答, 用于JsonCustom等不包装的输出
+	RawResponse bool
+}
+
+type DocOption func(*RouteDoc)
+
+func DocSummary(summary string) DocOption {
+	return func(d *RouteDoc) {
+		d.Summary = summary
+	}
+}
+
+func DocDescription(description string) DocOption {
+	return func(d *RouteDoc) {
+		d.Description = description
+	}
+}
+
+func DocOperationID(id string) DocOption {
+	return func(d *RouteDoc) {
+		d.OperationID = id
+	}
+}
+
+// DocTags 追加在group的tags之后
+func DocTags(tags ...string) DocOption {
+	return func(d *RouteDoc) {
+		d.Tags = append(d.Tags, tags...)
+	}
+}
+
+func DocDeprecated() DocOption {
+	return func(d *RouteDoc) {
+		d.Deprecated = true
+	}
+}
+
+func DocHidden() DocOption {
+	return func(d *RouteDoc) {
+		d.Hidden = true
+	}
+}
+
+func DocConsumes(contentTypes ...string) DocOption {
+	return func(d *RouteDoc) {
+		d.Consumes = contentTypes
+	}
+}
+
+// DocRequest v为参数结构体的零值或指针, 如DocRequest(CreateUserReq{})
+func DocRequest(v interface{}) DocOption {
+	return func(d *RouteDoc) {
+		d.Request = reflect.TypeOf(v)
+	}
+}
+
+// DocResponse v为data的零值或指针, 文档中包装为{status, message, data}
+func DocResponse(v interface{}) DocOption {
+	return func(d *RouteDoc) {
+		d.Response = reflect.TypeOf(v)
+		d.RawResponse = false
+	}
+}
+
+// DocRawResponse v为完整应答结构, 不包装
+func DocRawResponse(v interface{}) DocOption {
+	return func(d *RouteDoc) {
+		d.Response = reflect.TypeOf(v)
+		d.RawResponse = true
+	}
+}
+
+// RouteInfo 已注册的路由
+type RouteInfo struct {
+	Method  string
+	Path    string
+	Handler string
+	Doc     *RouteDoc
+}
+
+// Doc 返回同路径的子group, 其下注册的路由使用该文档信息
+func (g *Group) Doc(opts ...DocOption) *Group {
+	group := g.Group("")
+	doc := &RouteDoc{Tags: append([]string(nil), g.tags...)}
+	if g.doc != nil {
+		*doc = *g.doc
+		doc.Tags = append([]string(nil), g.doc.Tags...)
+	}
+	for _, opt := range opts {
+		opt(doc)
+	}
+	group.doc = doc
+	return group
+}
+
+// Tags 设置之后注册路由的文档tag, 子group继承
+func (g *Group) Tags(tags ...string) *Group {
+	g.tags = append(g.tags, tags...)
+	return g
+}
+
+// routeDoc 注册路由时使用的文档信息
+func (g *Group) routeDoc() *RouteDoc {
+	if g.doc != nil {
+		return g.doc
+	}
+	if len(g.tags) == 0 {
+		return nil
+	}
+	return &RouteDoc{Tags: append([]string(nil), g.tags...)}
+}
+
+// Routes 已注册的路由, 按path和method排序
+func (e *Engine) Routes() []RouteInfo {
+	routes := make([]RouteInfo, len(e.routes))
+	copy(routes, e.routes)
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+// RouteList 实现server.RouteLister
+func (e *Engine) RouteList() []gServer.Route {
+	routes := e.Routes()
+	list := make([]gServer.Route, 0, len(routes))
+	for _, route := range routes {
+		list = append(list, gServer.Route{Method: route.Method, Path: route.Path, Handler: route.Handler})
+	}
+	return list
+}
+
+func handlerName(h core) string {
+	if h == nil {
+		return ""
+	}
+	return gServer.FuncName(h)
+}
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+
+	gCtx "github.com/wangshanqi84-gif/sagittarius/cores/context"
+	"github.com/wangshanqi84-gif/sagittarius/cores/idempotency"
+)
+
+const (
+	idempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// IdempotencyHandler 按Idempotency-Key header保证请求只执行一次
+// 相同key重放已保存的应答, 处理中的重复请求返回409, key对应不同请求返回422
+// 5xx应答, 业务错误(JsonErr返回的非0 status)和panic不保存, 客户端可使用相同key重试; redis出错时返回503
+// 需放在CompressHandler之后, AuthHandler之后时key按principal隔离
+func IdempotencyHandler(g *idempotency.Guard) core {
+	return func(c *Context) {
+		if !g.Match(c.r.Method) {
+			c.Next()
+			return
+		}
+		key := gCtx.GetUberHttpIdempotencyHeader(c.r.Header)
+		if key == "" {
+			if g.Required() {
+				_ = c.HttpError(http.StatusBadRequest, idempotency.ErrKeyRequired.Error())
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+		if !idempotency.ValidKey(key) {
+			_ = c.HttpError(http.StatusBadRequest, idempotency.ErrInvalidKey.Error())
+			c.Abort()
+			return
+		}
+		scoped := key
+		if p, ok := c.Principal(); ok {
+			scoped = p.Subject + ":" + key
+		}
+		fp := idempotency.Fingerprint([]byte(c.r.Method), []byte(c.r.URL.RequestURI()), c.reqBody)
+		entry, rec, err := g.Begin(c.ctx, scoped, fp)
+		switch {
+		case errors.Is(err, idempotency.ErrInProgress):
+			c.w.Header().Set("Retry-After", "1")
+			_ = c.HttpError(http.StatusConflict, err.Error())
+			c.Abort()
+			return
+		case errors.Is(err, idempotency.ErrMismatch):
+			_ = c.HttpError(http.StatusUnprocessableEntity, err.Error())
+			c.Abort()
+			return
+		case err != nil:
+			_ = c.HttpError(http.StatusServiceUnavailable, "idempotency store unavailable")
+			c.Abort()
+			return
+		}
+		if rec != nil {
+			c.replay(rec)
+			c.Abort()
+			return
+		}
+
+		rw := &recordWriter{ResponseWriter: c.w, status: http.StatusOK}
+		c.w = rw
+		// 客户端断开后ctx已取消, 保存和释放使用不随请求取消的ctx, 避免key一直处于处理中
+		storeCtx := context.WithoutCancel(c.ctx)
+		completed := false
+		defer func() {
+			c.w = rw.ResponseWriter
+			if completed {
+				return
+			}
+			// 未正常结束(panic)时释放key, 之后由PanicHandler处理
+			_ = entry.Release(storeCtx)
+		}()
+		c.Next()
+
+		if rw.status >= http.StatusInternalServerError || rw.streamed || c.bizStatus != 0 {
+			_ = entry.Release(storeCtx)
+		} else {
+			header := make(map[string][]string, len(rw.Header()))
+			for k, vs := range rw.Header() {
+				if http.CanonicalHeaderKey(k) == http.CanonicalHeaderKey(gCtx.UberRequestIDKey()) {
+					continue
+				}
+				header[k] = vs
+			}
+			_ = entry.Complete(storeCtx, &idempotency.Record{
+				Status: rw.status,
+				Header: header,
+				Body:   rw.buf.Bytes(),
+			})
+		}
+		completed = true
+	}
+}
+
+// replay 输出已保存的应答
+func (c *Context) replay(rec *idempotency.Record) {
+	header := c.w.Header()
+	for k, vs := range rec.Header {
+		header[k] = vs
+	}
+	header.Set(idempotentReplayedHeader, "true")
+	c.w.WriteHeader(rec.Status)
+	_, _ = c.w.Write(rec.Body)
+	c.respData = map[string]interface{}{
+		"httpCode": rec.Status,
+		"replayed": true,
+	}
+}
+
+// recordWriter 记录应答用于保存, 同时正常输出
+type recordWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	streamed    bool
+	buf         bytes.Buffer
+}
+
+func (w *recordWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	if code >= 100 && code < 200 {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.wroteHeader = true
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *recordWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.buf.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// FlushError 流式输出的应答不保存
+func (w *recordWriter) FlushError() error {
+	w.streamed = true
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *recordWriter) Flush() {
+	_ = w.FlushError()
+}
+
+func (w *recordWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
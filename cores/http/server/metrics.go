@@ -0,0 +1,110 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	gCtx "github.com/wangshanqi84-gif/sagittarius/cores/context"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// unmatchedRoute 未匹配路由时的route标签, 避免原始url导致标签膨胀
+	unmatchedRoute = "unmatched"
+)
+
+var (
+	serverRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_server_requests_total",
+		Help: "Total number of HTTP requests handled by the server.",
+	}, []string{"upstream", "method", "route", "status"})
+	serverDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_server_request_duration_seconds",
+		Help:    "Histogram of HTTP request latency (seconds) handled by the server.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"upstream", "method", "route"})
+	serverInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "http_server_requests_in_flight",
+		Help: "Number of HTTP requests currently being handled by the server.",
+	}, []string{"upstream", "method", "route"})
+	serverErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_server_request_errors_total",
+		Help: "Total number of HTTP requests answered with status >= 400 or a non-zero business code.",
+	}, []string{"upstream", "method", "route", "status", "code"})
+)
+
+func init() {
+	prometheus.MustRegister(serverRequests, serverDuration, serverInFlight, serverErrors)
+}
+
+// MetricsHandler prometheus RED指标, route标签为路由模板, upstream标签为上游服务, 非服务调用为空
+// 放在PanicHandler之后, panic的请求记为500
+// 注册在engine上时框架直接应答的404/405/413等也会记录, 未匹配路由的route标签为unmatched
+func MetricsHandler() core {
+	return func(c *Context) {
+		method := c.Request().Method
+		upstream := gCtx.GetUberHttpHeader(c.Request().Header)
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		start := time.Now()
+		inFlight := serverInFlight.WithLabelValues(upstream, method, route)
+		inFlight.Inc()
+
+		sw := &statusWriter{ResponseWriter: c.w, status: http.StatusOK}
+		c.w = sw
+		finished := false
+		defer func() {
+			c.w = sw.ResponseWriter
+			inFlight.Dec()
+			status := sw.status
+			if !finished {
+				status = http.StatusInternalServerError
+			}
+			code := strconv.Itoa(status)
+			serverRequests.WithLabelValues(upstream, method, route, code).Inc()
+			serverDuration.WithLabelValues(upstream, method, route).Observe(time.Since(start).Seconds())
+			if status >= http.StatusBadRequest || c.bizStatus != 0 {
+				serverErrors.WithLabelValues(upstream, method, route, code, strconv.Itoa(c.bizStatus)).Inc()
+			}
+		}()
+		c.Next()
+		finished = true
+	}
+}
+
+// statusWriter 记录应答状态码
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader && code >= 200 {
+		w.wroteHeader = true
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) FlushError() error {
+	w.wroteHeader = true
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *statusWriter) Flush() {
+	_ = w.FlushError()
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
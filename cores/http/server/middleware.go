@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/wangshanqi84-gif/sagittarius/cores/auth"
 	gCtx "github.com/wangshanqi84-gif/sagittarius/cores/context"
 	gErrors "github.com/wangshanqi84-gif/sagittarius/cores/errors"
 	"github.com/wangshanqi84-gif/sagittarius/cores/logger"
@@ -44,6 +45,19 @@ func PanicHandler(lgr *logger.Logger) core {
 	}
 }
 
+// RequestIDHandler 沿用上游传入的请求ID或生成新ID, 写入Ctx()并在应答header返回
+func RequestIDHandler() core {
+	return func(c *Context) {
+		id := gCtx.GetUberHttpRequestIDHeader(c.Request().Header)
+		if !gCtx.ValidRequestID(id) {
+			id = gCtx.NewRequestID()
+		}
+		c.ctx = gCtx.NewRequestIDContext(c.ctx, id)
+		gCtx.SetUberHttpRequestIDHeader(c.Writer().Header(), id)
+		c.Next()
+	}
+}
+
 func TracingHandler(tracer opentracing.Tracer) core {
 	return func(c *Context) {
 		spanContext, err := tracer.Extract(
@@ -138,10 +152,20 @@ func LogHandler(lgr *logger.Logger, requestEnable bool) core {
 				"Method": c.Request().URL.String(),
 				"Cost":   fmt.Sprintf("%dms", time.Now().UnixMilli()-start),
 			}
+			if p, ok := auth.FromContext(c.ctx); ok {
+				logData["Principal"] = p
+			}
 			if requestEnable && len(c.reqBody) != 0 {
 				logData["Request"] = string(c.reqBody)
 			}
-			if c.respData != nil && !c.logWithoutResp {
+			if c.stream != nil {
+				// 推送流只记录事件数和持续时间
+				events, duration := c.stream.stats()
+				logData["Stream"] = map[string]interface{}{
+					"Events":   events,
+					"Duration": fmt.Sprintf("%dms", duration.Milliseconds()),
+				}
+			} else if c.respData != nil && !c.logWithoutResp {
 				logData["Response"] = c.respData
 			}
 			bs, e := json.Marshal(logData)
@@ -0,0 +1,281 @@
+package server
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/wangshanqi84-gif/sagittarius/cores/http/openapi"
+)
+
+const (
+	swaggerUICDN = "https://unpkg.com/swagger-ui-dist@5"
+)
+
+var (
+	// openapiMethods OpenAPI支持的method, CONNECT不在其中
+	openapiMethods = map[string]bool{
+		http.MethodGet: true, http.MethodPut: true, http.MethodPost: true, http.MethodDelete: true,
+		http.MethodOptions: true, http.MethodHead: true, http.MethodPatch: true, http.MethodTrace: true,
+	}
+
+	swaggerUITemplate = template.Must(template.New("swagger").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<link rel="stylesheet" href="{{.CDN}}/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="{{.CDN}}/swagger-ui-bundle.js"></script>
+<script>
+window.onload = function() {
+	window.ui = SwaggerUIBundle({url: "{{.SpecURL}}", dom_id: "#swagger-ui"});
+};
+</script>
+</body>
+</html>
+`))
+)
+
+type OpenAPIOption func(*openapiOptions)
+
+type openapiOptions struct {
+	info      openapi.Info
+	servers   []openapi.Server
+	swaggerUI string
+	cdn       string
+}
+
+func OpenAPITitle(title string) OpenAPIOption {
+	return func(o *openapiOptions) {
+		o.info.Title = title
+	}
+}
+
+func OpenAPIVersion(version string) OpenAPIOption {
+	return func(o *openapiOptions) {
+		o.info.Version = version
+	}
+}
+
+func OpenAPIDescription(description string) OpenAPIOption {
+	return func(o *openapiOptions) {
+		o.info.Description = description
+	}
+}
+
+func OpenAPIServers(urls ...string) OpenAPIOption {
+	return func(o *openapiOptions) {
+		for _, u := range urls {
+			o.servers = append(o.servers, openapi.Server{URL: u})
+		}
+	}
+}
+
+// OpenAPISwaggerUI 在path提供Swagger UI页面
+func OpenAPISwaggerUI(path string) OpenAPIOption {
+	return func(o *openapiOptions) {
+		o.swaggerUI = path
+	}
+}
+
+// OpenAPISwaggerUICDN swagger-ui-dist静态资源地址, 内网环境可指向私有镜像
+func OpenAPISwaggerUICDN(url string) OpenAPIOption {
+	return func(o *openapiOptions) {
+		o.cdn = strings.TrimRight(url, "/")
+	}
+}
+
+func newOpenAPIOptions(opts []OpenAPIOption) openapiOptions {
+	o := openapiOptions{
+		info: openapi.Info{Title: "API", Version: "1.0.0"},
+		cdn:  swaggerUICDN,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// OpenAPI 根据已注册路由生成OpenAPI 3.1文档
+func (e *Engine) OpenAPI(opts ...OpenAPIOption) *openapi.Document {
+	o := newOpenAPIOptions(opts)
+	doc := &openapi.Document{
+		OpenAPI: openapi.Version,
+		Info:    o.info,
+		Servers: o.servers,
+		Paths:   make(map[string]*openapi.PathItem),
+	}
+	r := openapi.NewReflector()
+	tags := make(map[string]bool)
+	for _, route := range e.Routes() {
+		if !openapiMethods[route.Method] || (route.Doc != nil && route.Doc.Hidden) {
+			continue
+		}
+		path, params := openapiPath(route.Path)
+		item, has := doc.Paths[path]
+		if !has {
+			item = &openapi.PathItem{}
+			doc.Paths[path] = item
+		}
+		op := buildOperation(r, route, params)
+		for _, t := range op.Tags {
+			tags[t] = true
+		}
+		(*item)[strings.ToLower(route.Method)] = op
+	}
+	if len(r.Schemas) > 0 {
+		doc.Components = &openapi.Components{Schemas: r.Schemas}
+	}
+	for t := range tags {
+		doc.Tags = append(doc.Tags, openapi.Tag{Name: t})
+	}
+	sort.Slice(doc.Tags, func(i, j int) bool {
+		return doc.Tags[i].Name < doc.Tags[j].Name
+	})
+	return doc
+}
+
+// ServeOpenAPI 在path提供OpenAPI文档, 首次请求时生成
+// 文档路由使用当前已注册的全局中间件, 不出现在文档中
+func (e *Engine) ServeOpenAPI(path string, opts ...OpenAPIOption) {
+	o := newOpenAPIOptions(opts)
+	var (
+		once sync.Once
+		spec []byte
+		err  error
+	)
+	g := e.Group.Doc(DocHidden())
+	g.GET(path, func(c *Context) {
+		once.Do(func() {
+			spec, err = json.Marshal(e.OpenAPI(opts...))
+		})
+		if err != nil {
+			_ = c.HttpError(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.LogWithoutResp()
+		c.w.Header().Set("Content-Type", MIMEJSON)
+		c.w.WriteHeader(http.StatusOK)
+		_, _ = c.w.Write(spec)
+	})
+	if o.swaggerUI == "" {
+		return
+	}
+	g.GET(o.swaggerUI, func(c *Context) {
+		c.LogWithoutResp()
+		c.w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		c.w.WriteHeader(http.StatusOK)
+		_ = swaggerUITemplate.Execute(c.w, map[string]string{
+			"Title":   o.info.Title,
+			"CDN":     o.cdn,
+			"SpecURL": path,
+		})
+	})
+}
+
+// openapiPath 将catch-all段转换为参数形式, 返回参数名
+func openapiPath(path string) (string, []string) {
+	segs := splitPath(path)
+	var params []string
+	for idx, s := range segs {
+		switch {
+		case s[0] == '*':
+			params = append(params, s[1:])
+			segs[idx] = "{" + s[1:] + "}"
+		case isParamSegment(s):
+			params = append(params, s[1:len(s)-1])
+		}
+	}
+	return "/" + strings.Join(segs, "/"), params
+}
+
+func buildOperation(r *openapi.Reflector, route RouteInfo, params []string) *openapi.Operation {
+	doc := route.Doc
+	if doc == nil {
+		doc = &RouteDoc{}
+	}
+	op := &openapi.Operation{
+		Tags:        doc.Tags,
+		Summary:     doc.Summary,
+		Description: doc.Description,
+		OperationID: doc.OperationID,
+		Deprecated:  doc.Deprecated,
+		Responses:   map[string]*openapi.Response{},
+	}
+	var fields []openapi.Field
+	if doc.Request != nil {
+		fields = r.Fields(doc.Request)
+	}
+	isPath := make(map[string]bool, len(params))
+	for _, name := range params {
+		isPath[name] = true
+		p := &openapi.Parameter{Name: name, In: "path", Required: true, Schema: &openapi.Schema{Type: "string"}}
+		for _, f := range fields {
+			if f.Name == name {
+				p.Schema = f.Schema
+				break
+			}
+		}
+		op.Parameters = append(op.Parameters, p)
+	}
+	if doc.Request != nil {
+		switch route.Method {
+		case http.MethodGet, http.MethodHead, http.MethodDelete, http.MethodOptions:
+			// 无body的method通过query绑定
+			for _, f := range fields {
+				if isPath[f.Name] {
+					continue
+				}
+				op.Parameters = append(op.Parameters, &openapi.Parameter{
+					Name:        f.Name,
+					In:          "query",
+					Required:    f.Required,
+					Description: f.Schema.Description,
+					Schema:      f.Schema,
+				})
+			}
+		default:
+			consumes := doc.Consumes
+			if len(consumes) == 0 {
+				consumes = []string{MIMEJSON}
+			}
+			body := &openapi.RequestBody{Required: true, Content: map[string]*openapi.MediaType{}}
+			schema := r.Schema(doc.Request)
+			for _, ct := range consumes {
+				body.Content[ct] = &openapi.MediaType{Schema: schema}
+			}
+			op.RequestBody = body
+		}
+	}
+	resp := &openapi.Response{Description: "OK"}
+	if doc.Response != nil {
+		resp.Content = map[string]*openapi.MediaType{
+			MIMEJSON: {Schema: responseSchema(r, doc.Response, doc.RawResponse)},
+		}
+	}
+	op.Responses["200"] = resp
+	return op
+}
+
+// responseSchema Ok/JsonOK的应答包装为Response结构
+func responseSchema(r *openapi.Reflector, t reflect.Type, raw bool) *openapi.Schema {
+	if raw {
+		return r.Schema(t)
+	}
+	return &openapi.Schema{
+		Type: "object",
+		Properties: map[string]*openapi.Schema{
+			"status":  {Type: "integer", Format: "int64"},
+			"message": {Type: "string"},
+			"data":    r.Schema(t),
+		},
+		Required: []string{"status", "message"},
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	gCtx "github.com/wangshanqi84-gif/sagittarius/cores/context"
+	"github.com/wangshanqi84-gif/sagittarius/cores/ratelimit"
+)
+
+// RateLimitKeyFunc 提取限流key, 返回空字符串时不限流
+type RateLimitKeyFunc func(c *Context) string
+
+// LimitByIP 按客户端ip限流
+func LimitByIP(c *Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// LimitByRoute 按method+路由模板限流
+func LimitByRoute(c *Context) string {
+	return "route:" + c.Request().Method + " " + c.FullPath()
+}
+
+// LimitByService 按上游服务限流, 非服务间调用不限流
+func LimitByService(c *Context) string {
+	td, ok := gCtx.FromClientContext(c.Ctx())
+	if !ok || td.ServiceName == "" {
+		return ""
+	}
+	return "service:" + strings.Join([]string{td.Namespace, td.Product, td.ServiceName}, ".")
+}
+
+// LimitGlobal 所有请求共用一个限额
+func LimitGlobal(_ *Context) string {
+	return "global"
+}
+
+// LimitKeyFunc 根据配置名称返回key提取方法, 未知名称返回nil
+func LimitKeyFunc(name string) RateLimitKeyFunc {
+	switch name {
+	case ratelimit.KeyIP:
+		return LimitByIP
+	case ratelimit.KeyRoute:
+		return LimitByRoute
+	case ratelimit.KeyService:
+		return LimitByService
+	case ratelimit.KeyGlobal:
+		return LimitGlobal
+	}
+	return nil
+}
+
+// RateLimitHandler 超过限额时返回429, limiter出错时放行
+// LimitByService依赖TracingHandler写入的上游信息, 需放在其后
+func RateLimitHandler(limiter ratelimit.Limiter, keyFunc RateLimitKeyFunc) core {
+	if keyFunc == nil {
+		keyFunc = LimitByIP
+	}
+	return func(c *Context) {
+		key := keyFunc(c)
+		if key == "" {
+			c.Next()
+			return
+		}
+		res, err := limiter.Allow(c.Ctx(), key)
+		if err != nil {
+			c.Next()
+			return
+		}
+		header := c.Writer().Header()
+		header.Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+		header.Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		if !res.Allowed {
+			if res.RetryAfter > 0 {
+				header.Set("Retry-After", strconv.FormatInt(int64(math.Ceil(res.RetryAfter.Seconds())), 10))
+			}
+			_ = c.HttpError(http.StatusTooManyRequests, "too many requests!")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,196 @@
+package server
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	gErrors "github.com/wangshanqi84-gif/sagittarius/cores/errors"
+
+	"github.com/go-playground/form/v4"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	renderersMu sync.RWMutex
+	_renderers  = map[string]IRenderer{
+		MIMEJSON:                newJsonRenderer(),
+		MIMEXML:                 newXmlRenderer(),
+		MIMETextXML:             newXmlRenderer(),
+		MIMEForm:                newFormRenderer(),
+		MIMEProtobuf:            newProtoRenderer(),
+		"application/protobuf":  newProtoRenderer(),
+		MIMEMsgpack:             newMsgpackRenderer(),
+		"application/x-msgpack": newMsgpackRenderer(),
+	}
+)
+
+// Response Ok/Err统一应答结构
+type Response struct {
+	XMLName xml.Name         `json:"-" xml:"response" msgpack:"-" form:"-"`
+	Status  int              `json:"status" xml:"status" msgpack:"status" form:"status"`
+	Message string           `json:"message" xml:"message" msgpack:"message" form:"message"`
+	Data    interface{}      `json:"data,omitempty" xml:"data,omitempty" msgpack:"data,omitempty" form:"data,omitempty"`
+	Details []gErrors.Detail `json:"details,omitempty" xml:"detail,omitempty" msgpack:"details,omitempty" form:"details,omitempty"`
+}
+
+type IRenderer interface {
+	Marshal(v interface{}) ([]byte, error)
+}
+
+// RegisterRenderer 注册应答渲染器, mediaType不含参数, 如"application/json"
+func RegisterRenderer(mediaType string, renderer IRenderer) {
+	renderersMu.Lock()
+	defer renderersMu.Unlock()
+
+	_renderers[strings.ToLower(mediaType)] = renderer
+}
+
+func Renderer(mediaType string) (IRenderer, bool) {
+	renderersMu.RLock()
+	defer renderersMu.RUnlock()
+
+	r, has := _renderers[strings.ToLower(mediaType)]
+	return r, has
+}
+
+type acceptItem struct {
+	mediaType string
+	q         float64
+}
+
+// negotiate 根据Accept选择已注册的渲染器, 无匹配时使用json
+func negotiate(accept string) string {
+	if accept == "" {
+		return MIMEJSON
+	}
+	var items []acceptItem
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if qs, has := params["q"]; has {
+			if q, err = strconv.ParseFloat(qs, 64); err != nil {
+				continue
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		items = append(items, acceptItem{mediaType: mediaType, q: q})
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].q > items[j].q
+	})
+
+	renderersMu.RLock()
+	defer renderersMu.RUnlock()
+
+	for _, item := range items {
+		if item.mediaType == "*/*" {
+			return MIMEJSON
+		}
+		if strings.HasSuffix(item.mediaType, "/*") {
+			prefix := strings.TrimSuffix(item.mediaType, "*")
+			if strings.HasPrefix(MIMEJSON, prefix) {
+				return MIMEJSON
+			}
+			var matched []string
+			for mt := range _renderers {
+				if strings.HasPrefix(mt, prefix) {
+					matched = append(matched, mt)
+				}
+			}
+			if len(matched) > 0 {
+				sort.Strings(matched)
+				return matched[0]
+			}
+			continue
+		}
+		if _, has := _renderers[item.mediaType]; has {
+			return item.mediaType
+		}
+	}
+	return MIMEJSON
+}
+
+type JsonRenderer struct{}
+
+func newJsonRenderer() *JsonRenderer {
+	return &JsonRenderer{}
+}
+
+func (jr *JsonRenderer) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+type XmlRenderer struct{}
+
+func newXmlRenderer() *XmlRenderer {
+	return &XmlRenderer{}
+}
+
+func (xr *XmlRenderer) Marshal(v interface{}) ([]byte, error) {
+	return xml.Marshal(v)
+}
+
+type FormRenderer struct {
+	encoder *form.Encoder
+}
+
+func newFormRenderer() *FormRenderer {
+	return &FormRenderer{
+		encoder: form.NewEncoder(),
+	}
+}
+
+func (fr *FormRenderer) Marshal(v interface{}) ([]byte, error) {
+	vs, err := fr.encoder.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(vs.Encode()), nil
+}
+
+type MsgpackRenderer struct{}
+
+func newMsgpackRenderer() *MsgpackRenderer {
+	return &MsgpackRenderer{}
+}
+
+func (mr *MsgpackRenderer) Marshal(v interface{}) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+// ProtoRenderer 成功时直接输出data, data必须为proto.Message
+// 失败或data为空时输出google.rpc.Status
+type ProtoRenderer struct{}
+
+func newProtoRenderer() *ProtoRenderer {
+	return &ProtoRenderer{}
+}
+
+func (pr *ProtoRenderer) Marshal(v interface{}) ([]byte, error) {
+	if resp, ok := v.(*Response); ok {
+		if resp.Status != 0 || resp.Data == nil {
+			return proto.Marshal(&status.Status{
+				Code:    int32(resp.Status),
+				Message: resp.Message,
+			})
+		}
+		v = resp.Data
+	}
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, ErrNotProtoMessage
+	}
+	return proto.Marshal(msg)
+}
@@ -1,21 +1,38 @@
 package server
 
 import (
+	"fmt"
 	"net/http"
 )
 
+var (
+	anyMethods = []string{
+		http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodHead, http.MethodOptions, http.MethodDelete, http.MethodConnect,
+		http.MethodTrace,
+	}
+)
+
 type Group struct {
-	cores    []core
-	basePath string
-	svr      *Engine
+	cores      []core
+	basePath   string
+	svr        *Engine
+	bodyLimit  int64
+	streamBody bool
+	doc        *RouteDoc
+	tags       []string
 }
 
 func (g *Group) Group(basePath string) *Group {
 	path := g.calculateAbsolutePath(basePath)
 	group := &Group{
-		svr:      g.svr,
-		cores:    nil,
-		basePath: path,
+		svr:        g.svr,
+		cores:      nil,
+		basePath:   path,
+		bodyLimit:  g.bodyLimit,
+		streamBody: g.streamBody,
+		doc:        g.doc,
+		tags:       append([]string(nil), g.tags...),
 	}
 	if len(g.cores) > 0 {
 		group.cores = append(group.cores, g.cores...)
@@ -23,6 +40,11 @@ func (g *Group) Group(basePath string) *Group {
 	return group
 }
 
+// Scopes 返回同路径的子group, 其下路由要求principal拥有全部scope
+func (g *Group) Scopes(scopes ...string) *Group {
+	return g.Group("").Use(RequireScopes(scopes...))
+}
+
 func (g *Group) calculateAbsolutePath(relativePath string) string {
 	return joinPaths(g.basePath, relativePath)
 }
@@ -40,10 +62,38 @@ func (g *Group) Use(cores ...core) *Group {
 	return g
 }
 
+// BodyLimit 设置之后注册路由的body大小限制, 0:使用engine配置 <0:不限制
+func (g *Group) BodyLimit(n int64) *Group {
+	g.bodyLimit = n
+	return g
+}
+
+// StreamBody 设置之后注册的路由不预读body, 由业务通过Request().Body流式读取
+// 此时不会进行body解密, Bind只解析multipart表单, 不解析其他body
+func (g *Group) StreamBody(stream bool) *Group {
+	g.streamBody = stream
+	return g
+}
+
 func (g *Group) handle(httpMethod, relativePath string, cores ...core) *Group {
 	absolutePath := g.calculateAbsolutePath(relativePath)
+	opts := routeOptions{
+		middlewares: len(g.cores),
+		bodyLimit:   g.bodyLimit,
+		streamBody:  g.streamBody,
+	}
+	var handler string
+	if len(cores) > 0 {
+		handler = handlerName(cores[len(cores)-1])
+	}
 	cores = g.combineHandlers(cores...)
-	g.svr.addRoute(httpMethod, absolutePath, cores...)
+	g.svr.addRoute(httpMethod, absolutePath, opts, cores...)
+	g.svr.routes = append(g.svr.routes, RouteInfo{
+		Method:  httpMethod,
+		Path:    absolutePath,
+		Handler: handler,
+		Doc:     g.routeDoc(),
+	})
 	return g
 }
 
@@ -66,3 +116,27 @@ func (g *Group) PATCH(relativePath string, cores ...core) *Group {
 func (g *Group) PUT(relativePath string, cores ...core) *Group {
 	return g.handle(http.MethodPut, relativePath, cores...)
 }
+
+func (g *Group) HEAD(relativePath string, cores ...core) *Group {
+	return g.handle(http.MethodHead, relativePath, cores...)
+}
+
+func (g *Group) OPTIONS(relativePath string, cores ...core) *Group {
+	return g.handle(http.MethodOptions, relativePath, cores...)
+}
+
+// Any 注册所有标准method
+func (g *Group) Any(relativePath string, cores ...core) *Group {
+	for _, method := range anyMethods {
+		g.handle(method, relativePath, cores...)
+	}
+	return g
+}
+
+// Handle 注册任意method, 可用于自定义method
+func (g *Group) Handle(httpMethod, relativePath string, cores ...core) *Group {
+	if !validMethod(httpMethod) {
+		panic(fmt.Sprintf("http method %q is not valid", httpMethod))
+	}
+	return g.handle(httpMethod, relativePath, cores...)
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -11,13 +12,15 @@ import (
 	"sync"
 
 	"github.com/wangshanqi84-gif/sagittarius/cores/http/crypto"
+	gServer "github.com/wangshanqi84-gif/sagittarius/cores/server"
 
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
 )
 
 const (
-	bodyMaxByte = 1024 * 1024 * 4
+	bodyMaxByte     = 1024 * 1024 * 4
+	multipartMemory = 1024 * 1024 * 32
 )
 
 type Option func(*Engine)
@@ -46,12 +49,64 @@ func Crypto(c crypto.ICrypto) Option {
 	}
 }
 
+// EncryptResponse 使用Crypto加密Json/Xml/Form应答, 应答头中会携带加密标记供客户端识别
+func EncryptResponse(enable bool) Option {
+	return func(e *Engine) {
+		e.encryptResponse = enable
+	}
+}
+
 func UseH2C(h2c bool) Option {
 	return func(e *Engine) {
 		e.UseH2C = h2c
 	}
 }
 
+// MaxBodyBytes 默认body大小限制, <=0:不限制, 可通过Group.BodyLimit按路由覆盖
+func MaxBodyBytes(n int64) Option {
+	return func(e *Engine) {
+		e.bodyLimit = n
+	}
+}
+
+// MultipartMemory multipart解析时内存中保存的最大字节数, 超出部分写入临时文件
+// 未配置Crypto时multipart body不预读, 与StreamBody路由相同, 不支持HMAC签名校验
+func MultipartMemory(n int64) Option {
+	return func(e *Engine) {
+		e.multipartMemory = n
+	}
+}
+
+// AutoPreflight 开启后未注册OPTIONS的路由将对任意Origin应答预检请求
+// 建议使用CORSHandler配置跨域策略
+func AutoPreflight(enable bool) Option {
+	return func(e *Engine) {
+		e.autoPreflight = enable
+	}
+}
+
+// TrustedProxies 可信代理的ip或CIDR, 只有来自可信代理的请求才使用X-Forwarded-For/X-Real-IP获取客户端ip
+// 格式错误的项忽略
+func TrustedProxies(proxies ...string) Option {
+	return func(e *Engine) {
+		for _, p := range proxies {
+			p = strings.TrimSpace(p)
+			if !strings.Contains(p, "/") {
+				if ip := net.ParseIP(p); ip != nil {
+					bits := 128
+					if ip.To4() != nil {
+						bits = 32
+					}
+					p = fmt.Sprintf("%s/%d", p, bits)
+				}
+			}
+			if _, cidr, err := net.ParseCIDR(p); err == nil {
+				e.trustedProxies = append(e.trustedProxies, cidr)
+			}
+		}
+	}
+}
+
 func OnStop(fs ...func()) Option {
 	return func(e *Engine) {
 		e.onStop = append(e.onStop, fs...)
@@ -70,11 +125,26 @@ type Engine struct {
 	keyFile  string
 	crypto   crypto.ICrypto
 	onStop   []func()
+
+	autoPreflight   bool
+	encryptResponse bool
+	bodyLimit       int64
+	multipartMemory int64
+	trustedProxies  []*net.IPNet
+	routes          []RouteInfo
+
+	tracker   *gServer.Tracker
+	streamsMu sync.Mutex
+	streams   map[*SSEStream]struct{}
 }
 
 func New(opts ...Option) *Engine {
 	e := &Engine{
-		tree: newTree(),
+		tree:            newTree(),
+		bodyLimit:       bodyMaxByte,
+		multipartMemory: multipartMemory,
+		tracker:         gServer.NewTracker(),
+		streams:         make(map[*SSEStream]struct{}),
 	}
 	group := &Group{
 		svr: e,
@@ -92,8 +162,8 @@ func New(opts ...Option) *Engine {
 	return e
 }
 
-func (e *Engine) addRoute(method string, path string, cores ...core) {
-	e.tree.addRoute(method, path, cores...)
+func (e *Engine) addRoute(method string, path string, opts routeOptions, cores ...core) {
+	e.tree.addRoute(method, path, opts, cores...)
 }
 
 func (e *Engine) NewGroup(basePath string) *Group {
@@ -126,78 +196,159 @@ func (e *Engine) Start(ctx context.Context) error {
 	return nil
 }
 
+// Drain 标记为未就绪, 请求继续处理, HTTP/1应答携带Connection: close使客户端重建连接
+func (e *Engine) Drain() {
+	e.tracker.Drain()
+}
+
+func (e *Engine) Ready() bool {
+	return e.tracker.Ready()
+}
+
+// InFlight 处理中的请求
+func (e *Engine) InFlight() []gServer.Call {
+	return e.tracker.InFlight()
+}
+
+// Stop 排空后关闭, 关闭SSE连接并等待处理中的请求结束
+// ctx结束时强制关闭, 返回的错误包含未完成的请求
 func (e *Engine) Stop(ctx context.Context) error {
+	e.Drain()
 	if len(e.onStop) > 0 {
 		for _, f := range e.onStop {
 			f()
 		}
 	}
-	return e.Shutdown(ctx)
+	e.closeStreams()
+	if err := e.Shutdown(ctx); err != nil {
+		timeoutErr := e.tracker.TimeoutError()
+		_ = e.Close()
+		if timeoutErr != nil {
+			return timeoutErr
+		}
+		return err
+	}
+	return nil
+}
+
+func (e *Engine) trackStream(s *SSEStream, add bool) {
+	e.streamsMu.Lock()
+	defer e.streamsMu.Unlock()
+	if add {
+		e.streams[s] = struct{}{}
+	} else {
+		delete(e.streams, s)
+	}
+}
+
+func (e *Engine) closeStreams() {
+	e.streamsMu.Lock()
+	streams := make([]*SSEStream, 0, len(e.streams))
+	for s := range e.streams {
+		streams = append(streams, s)
+	}
+	e.streamsMu.Unlock()
+	for _, s := range streams {
+		s.Close()
+	}
+}
+
+// trusted ip是否为可信代理
+func (e *Engine) trusted(ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, cidr := range e.trustedProxies {
+		if cidr.Contains(addr) {
+			return true
+		}
+	}
+	return false
 }
 
 func (e *Engine) handleHTTPRequest(c *Context) {
 	method := c.r.Method
 	path := c.r.URL.Path
 
-	// OPTIONS预检请求直接在这里处理
-	if method == http.MethodOptions {
-		origin := c.Request().Header.Get("Origin")
-		if origin != "" {
-			c.Writer().Header().Set("Access-Control-Allow-Origin", origin)
-			c.Writer().Header().Set("Access-Control-Allow-Methods",
-				"GET, POST, PUT, DELETE, PATCH, OPTIONS")
-			requestHeaders := c.Request().Header.Get("Access-Control-Request-Headers")
-			if requestHeaders != "" {
-				c.Writer().Header().Set("Access-Control-Allow-Headers", requestHeaders)
-			}
-			c.Writer().Header().Set("Access-Control-Allow-Credentials", "true")
-			c.Writer().Header().Set("Access-Control-Max-Age", "86400")
-		}
-		// OPTIONS请求返回
-		c.Writer().WriteHeader(http.StatusNoContent)
-		return
+	segs := splitPath(path)
+	leaf, values := e.tree.find(method, segs)
+	if leaf == nil && method == http.MethodHead {
+		// 未注册HEAD时由GET应答, body由net/http丢弃
+		leaf, values = e.tree.find(http.MethodGet, segs)
 	}
-	if e.tree[method] == nil {
-		_ = c.HttpError(404, "page not found!")
-		return
-	}
-	root := e.tree[method]
-	if len(path) == 1 && path[0] == '/' {
-		c.cores = root.cores
-	} else {
-		ss := strings.Split(path, "/")
-		var ns []string
-		for _, s := range ss {
-			if s != "" {
-				ns = append(ns, s)
-			}
-		}
-		current := e.tree[method]
-		for _, s := range ns {
-			if _, has := current.children[s]; !has {
-				if _, has = current.children[pathParamX]; !has {
-					_ = c.HttpError(404, "page not found!")
-					return
-				}
-				current = current.children[pathParamX]
-				c.addPathParam(current.name, s)
-			} else {
-				current = current.children[s]
-			}
+	if leaf == nil {
+		allow := e.tree.allowed(segs)
+		if len(allow) == 0 {
+			e.handleGlobal(c, func(c *Context) {
+				_ = c.HttpError(404, "page not found!")
+			})
+			return
 		}
-		c.cores = current.cores
-		if len(c.cores) == 0 {
-			_ = c.HttpError(404, "page not found!")
+		if method != http.MethodOptions {
+			e.handleGlobal(c, func(c *Context) {
+				methodNotAllowed(c, allow)
+			})
 			return
 		}
+		// 复用该路径的中间件, 使CORSHandler等可以处理预检请求
+		leaf, values = e.optionsLeaf(segs, allow)
+		c.cores = append(leaf.cores[:leaf.opts.middlewares:leaf.opts.middlewares], func(c *Context) {
+			e.handleOptions(c, allow)
+		})
+	} else {
+		c.cores = leaf.cores
+	}
+	c.fullPath = leaf.fullPath
+	for idx, name := range leaf.paramNames {
+		c.addPathParam(name, values[idx])
 	}
 	// 提前解析body
-	ioBody := http.MaxBytesReader(c.Writer(), c.Request().Body, bodyMaxByte)
+	if f := e.readBody(c, leaf.opts); f != nil {
+		e.handleGlobal(c, f)
+		return
+	}
+	c.do()
+	if c.stream != nil {
+		c.stream.Close()
+	}
+}
+
+// handleGlobal 框架直接应答的请求(404/405/413等)只执行engine上的全局中间件, 使指标、日志等能够记录
+func (e *Engine) handleGlobal(c *Context, f core) {
+	c.cores = append(e.Group.cores[:len(e.Group.cores):len(e.Group.cores)], f)
+	c.do()
+}
+
+// readBody 读取body, 失败时返回应答错误的core
+// 未配置Crypto时multipart不预读, 由MultipartForm流式解析, 内存占用受MultipartMemory限制
+// 配置Crypto时multipart需整体读入解密, Request().Body替换为解密后的内容
+func (e *Engine) readBody(c *Context, opts routeOptions) core {
+	limit := e.bodyLimit
+	if opts.bodyLimit != 0 {
+		limit = opts.bodyLimit
+	}
+	if limit > 0 {
+		c.Request().Body = http.MaxBytesReader(c.Writer(), c.Request().Body, limit)
+	}
+	multipart := parseMediaType(c.Request().Header.Get("Content-Type")) == MIMEMultipart
+	if opts.streamBody || (multipart && e.crypto == nil) {
+		c.streamBody = true
+		return nil
+	}
+	ioBody := c.Request().Body
 	defer ioBody.Close()
 	data, err := io.ReadAll(ioBody)
 	if err != nil {
-		_ = c.HttpError(499, fmt.Sprintf("request body decode error:%v", err.Error()))
-		return
+		var mbe *http.MaxBytesError
+		if errors.As(err, &mbe) {
+			return func(c *Context) {
+				_ = c.HttpError(http.StatusRequestEntityTooLarge, fmt.Sprintf("request body too large, limit:%d", mbe.Limit))
+			}
+		}
+		return func(c *Context) {
+			_ = c.HttpError(499, fmt.Sprintf("request body decode error:%v", err.Error()))
+		}
 	}
 	// Reset resp.Body so it can be use again
 	c.Request().Body = io.NopCloser(bytes.NewBuffer(data))
@@ -206,21 +357,61 @@ func (e *Engine) handleHTTPRequest(c *Context) {
 			var s string
 			s, err = e.crypto.Decrypt(string(data))
 			if err != nil {
-				_ = c.HttpError(499, fmt.Sprintf("request body decrypt error:%v", err.Error()))
-				return
+				return func(c *Context) {
+					_ = c.HttpError(499, fmt.Sprintf("request body decrypt error:%v", err.Error()))
+				}
 			}
 			data = []byte(s)
+			if multipart {
+				c.Request().Body = io.NopCloser(bytes.NewReader(data))
+			}
 		}
 		c.reqBody = data
 	}
-	c.do()
+	return nil
+}
+
+// optionsLeaf 优先使用GET路由, allow非空时必定能找到
+func (e *Engine) optionsLeaf(segs []string, allow []string) (*node, []string) {
+	if leaf, values := e.tree.find(http.MethodGet, segs); leaf != nil {
+		return leaf, values
+	}
+	for _, m := range allow {
+		if leaf, values := e.tree.find(m, segs); leaf != nil {
+			return leaf, values
+		}
+	}
+	return nil, nil
+}
+
+// handleOptions 未注册OPTIONS路由时的默认应答
+func (e *Engine) handleOptions(c *Context, allow []string) {
+	c.Writer().Header().Set("Allow", strings.Join(allow, ", "))
+	origin := c.Request().Header.Get("Origin")
+	if e.autoPreflight && origin != "" {
+		c.Writer().Header().Set("Access-Control-Allow-Origin", origin)
+		c.Writer().Header().Set("Access-Control-Allow-Methods", strings.Join(allow, ", "))
+		requestHeaders := c.Request().Header.Get("Access-Control-Request-Headers")
+		if requestHeaders != "" {
+			c.Writer().Header().Set("Access-Control-Allow-Headers", requestHeaders)
+		}
+		c.Writer().Header().Set("Access-Control-Allow-Credentials", "true")
+		c.Writer().Header().Set("Access-Control-Max-Age", "86400")
+	}
+	c.Writer().WriteHeader(http.StatusNoContent)
 }
 
 func (e *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	done := e.tracker.Begin(req.Method + " " + req.URL.Path)
+	defer done()
+	if !e.tracker.Ready() && req.ProtoMajor == 1 {
+		w.Header().Set("Connection", "close")
+	}
 	c := e.pool.Get().(*Context)
 	c.w = w
 	c.r = req
 	c.reset()
+	c.srv = e
 
 	e.handleHTTPRequest(c)
 
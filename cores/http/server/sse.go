@@ -0,0 +1,209 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrStreamClosed = errors.New("sse stream closed")
+)
+
+// SSEvent 服务端推送事件, Data为string/[]byte时原样输出, 其他类型json序列化
+type SSEvent struct {
+	ID    string
+	Event string
+	Data  interface{}
+	Retry time.Duration
+}
+
+type SSEOption func(*sseOptions)
+
+type sseOptions struct {
+	heartbeat time.Duration
+	retry     time.Duration
+}
+
+// SSEHeartbeat 心跳间隔, 定时发送注释行保持连接
+func SSEHeartbeat(d time.Duration) SSEOption {
+	return func(o *sseOptions) {
+		o.heartbeat = d
+	}
+}
+
+// SSERetry 建立连接时下发客户端重连间隔
+func SSERetry(d time.Duration) SSEOption {
+	return func(o *sseOptions) {
+		o.retry = d
+	}
+}
+
+type SSEStream struct {
+	c      *Context
+	srv    *Engine
+	rc     *http.ResponseController
+	mu     sync.Mutex
+	closed bool
+	done   chan struct{}
+	start  time.Time
+	events int
+}
+
+// SSE 将应答切换为text/event-stream, 之后只能通过SSEStream写入
+// 请求结束或服务Stop时stream自动关闭, 业务需监听Done退出
+func (c *Context) SSE(opts ...SSEOption) (*SSEStream, error) {
+	var o sseOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	s := &SSEStream{
+		c:     c,
+		rc:    http.NewResponseController(c.w),
+		done:  make(chan struct{}),
+		start: time.Now(),
+	}
+	header := c.w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no")
+	if c.r.ProtoMajor == 1 {
+		header.Set("Connection", "keep-alive")
+	}
+	c.w.WriteHeader(http.StatusOK)
+	if o.retry > 0 {
+		if _, err := c.w.Write([]byte("retry: " + strconv.FormatInt(o.retry.Milliseconds(), 10) + "\n\n")); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.rc.Flush(); err != nil {
+		return nil, err
+	}
+	c.stream = s
+	if c.srv != nil {
+		s.srv = c.srv
+		c.srv.trackStream(s, true)
+	}
+	go s.watch(o.heartbeat, c.r.Context().Done())
+	return s, nil
+}
+
+// LastEventID 客户端断线重连时携带的最后事件ID
+func (s *SSEStream) LastEventID() string {
+	return s.c.r.Header.Get("Last-Event-ID")
+}
+
+// Done 客户端断开或stream关闭
+func (s *SSEStream) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *SSEStream) Send(ev SSEvent) error {
+	var data string
+	switch d := ev.Data.(type) {
+	case nil:
+	case string:
+		data = d
+	case []byte:
+		data = string(d)
+	default:
+		bs, err := json.Marshal(d)
+		if err != nil {
+			return err
+		}
+		data = string(bs)
+	}
+	var sb strings.Builder
+	if ev.ID != "" {
+		sb.WriteString("id: " + oneLine(ev.ID) + "\n")
+	}
+	if ev.Event != "" {
+		sb.WriteString("event: " + oneLine(ev.Event) + "\n")
+	}
+	if ev.Retry > 0 {
+		sb.WriteString("retry: " + strconv.FormatInt(ev.Retry.Milliseconds(), 10) + "\n")
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		sb.WriteString("data: " + line + "\n")
+	}
+	sb.WriteString("\n")
+	if err := s.write(sb.String()); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.events++
+	s.mu.Unlock()
+	return nil
+}
+
+// Comment 发送注释行, 客户端会忽略
+func (s *SSEStream) Comment(text string) error {
+	return s.write(": " + oneLine(text) + "\n\n")
+}
+
+func (s *SSEStream) write(text string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrStreamClosed
+	}
+	if _, err := s.c.w.Write([]byte(text)); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// watch 客户端断开时关闭stream, heartbeat>0时定时发送心跳
+func (s *SSEStream) watch(heartbeat time.Duration, reqDone <-chan struct{}) {
+	var tick <-chan time.Time
+	if heartbeat > 0 {
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-tick:
+			if err := s.Comment("ping"); err != nil {
+				s.Close()
+				return
+			}
+		case <-reqDone:
+			s.Close()
+			return
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *SSEStream) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.closed = true
+	close(s.done)
+	if s.srv != nil {
+		s.srv.trackStream(s, false)
+	}
+}
+
+// stats 事件数和持续时间, 用于访问日志
+func (s *SSEStream) stats() (int, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.events, time.Since(s.start)
+}
+
+func oneLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
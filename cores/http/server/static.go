@@ -0,0 +1,234 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	staticParam = "filepath"
+)
+
+var (
+	// precompressed 按优先级排列的预压缩文件后缀
+	precompressed = []struct {
+		encoding string
+		ext      string
+	}{
+		{encoding: "br", ext: ".br"},
+		{encoding: "gzip", ext: ".gz"},
+	}
+)
+
+type StaticOption func(*staticOptions)
+
+type staticOptions struct {
+	index         string
+	spa           string
+	maxAge        time.Duration
+	precompressed bool
+}
+
+// StaticIndex 目录默认文件, 默认index.html
+func StaticIndex(name string) StaticOption {
+	return func(o *staticOptions) {
+		o.index = name
+	}
+}
+
+// StaticSPA 单页应用模式, 不带扩展名的路径找不到文件时返回fallback(如index.html)
+func StaticSPA(fallback string) StaticOption {
+	return func(o *staticOptions) {
+		o.spa = fallback
+	}
+}
+
+// StaticMaxAge 设置Cache-Control的max-age
+func StaticMaxAge(d time.Duration) StaticOption {
+	return func(o *staticOptions) {
+		o.maxAge = d
+	}
+}
+
+// StaticPrecompressed 是否查找.br/.gz预压缩文件, 默认开启
+func StaticPrecompressed(enable bool) StaticOption {
+	return func(o *staticOptions) {
+		o.precompressed = enable
+	}
+}
+
+// Static 将dir目录挂载到prefix下, 经过group中间件处理
+func (g *Group) Static(prefix string, dir string, opts ...StaticOption) *Group {
+	return g.StaticFS(prefix, os.DirFS(dir), opts...)
+}
+
+// StaticFS 将fs.FS(如embed.FS)挂载到prefix下
+func (g *Group) StaticFS(prefix string, fsys fs.FS, opts ...StaticOption) *Group {
+	o := staticOptions{
+		index:         "index.html",
+		precompressed: true,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	h := &staticHandler{
+		fsys: fsys,
+		opts: o,
+	}
+	relativePath := strings.TrimSuffix(prefix, "/") + "/*" + staticParam
+	return g.GET(relativePath, h.serve)
+}
+
+type staticHandler struct {
+	fsys  fs.FS
+	opts  staticOptions
+	etags sync.Map
+}
+
+func (h *staticHandler) serve(c *Context) {
+	// 清理路径, fs.ValidPath拒绝".."等非法路径
+	name := strings.TrimPrefix(path.Clean("/"+c.GetPathParam(staticParam)), "/")
+	if name == "" {
+		name = "."
+	}
+	if !fs.ValidPath(name) {
+		_ = c.HttpError(http.StatusNotFound, "page not found!")
+		return
+	}
+	f, fi, fname, err := h.open(name)
+	if err != nil && h.opts.spa != "" && path.Ext(name) == "" {
+		f, fi, fname, err = h.open(h.opts.spa)
+	}
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
+			_ = c.HttpError(http.StatusNotFound, "page not found!")
+			return
+		}
+		_ = c.HttpError(http.StatusInternalServerError, fmt.Sprintf("open file error:%v", err))
+		return
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	h.serveFile(c, fname, f, fi)
+}
+
+// open 打开文件, 目录时返回index文件
+func (h *staticHandler) open(name string) (fs.File, fs.FileInfo, string, error) {
+	f, fi, err := openFile(h.fsys, name)
+	if err != nil {
+		return nil, nil, name, err
+	}
+	if !fi.IsDir() {
+		return f, fi, name, nil
+	}
+	_ = f.Close()
+	if h.opts.index == "" {
+		return nil, nil, name, fs.ErrNotExist
+	}
+	name = path.Join(name, h.opts.index)
+	f, fi, err = openFile(h.fsys, name)
+	if err != nil {
+		return nil, nil, name, err
+	}
+	if fi.IsDir() {
+		_ = f.Close()
+		return nil, nil, name, fs.ErrNotExist
+	}
+	return f, fi, name, nil
+}
+
+func openFile(fsys fs.FS, name string) (fs.File, fs.FileInfo, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, nil, err
+	}
+	return f, fi, nil
+}
+
+func (h *staticHandler) serveFile(c *Context, name string, f fs.File, fi fs.FileInfo) {
+	header := c.Writer().Header()
+	ctype := mime.TypeByExtension(path.Ext(name))
+	if ctype != "" {
+		header.Set("Content-Type", ctype)
+	}
+	// 预压缩文件, 仅在能确定Content-Type时使用
+	if h.opts.precompressed && ctype != "" {
+		header.Add("Vary", "Accept-Encoding")
+		encs := acceptEncodings(c.Request().Header.Get("Accept-Encoding"))
+		for _, pc := range precompressed {
+			if _, has := encs[pc.encoding]; !has {
+				continue
+			}
+			cf, cfi, err := openFile(h.fsys, name+pc.ext)
+			if err != nil || cfi.IsDir() {
+				if cf != nil {
+					_ = cf.Close()
+				}
+				continue
+			}
+			defer func() {
+				_ = cf.Close()
+			}()
+			header.Set("Content-Encoding", pc.encoding)
+			name, f, fi = name+pc.ext, cf, cfi
+			break
+		}
+	}
+	rs, ok := f.(io.ReadSeeker)
+	if !ok {
+		bs, err := io.ReadAll(f)
+		if err != nil {
+			_ = c.HttpError(http.StatusInternalServerError, fmt.Sprintf("read file error:%v", err))
+			return
+		}
+		rs = bytes.NewReader(bs)
+	}
+	etag, err := h.etag(name, fi, rs)
+	if err != nil {
+		_ = c.HttpError(http.StatusInternalServerError, fmt.Sprintf("read file error:%v", err))
+		return
+	}
+	header.Set("ETag", etag)
+	if h.opts.maxAge > 0 {
+		header.Set("Cache-Control", fmt.Sprintf("max-age=%d", int64(h.opts.maxAge/time.Second)))
+	}
+	// ServeContent处理Range/If-None-Match/If-Modified-Since
+	http.ServeContent(c.Writer(), c.Request(), name, fi.ModTime(), rs)
+}
+
+// etag 有修改时间时使用大小+修改时间, 否则(如embed.FS)使用内容hash并缓存
+func (h *staticHandler) etag(name string, fi fs.FileInfo, rs io.ReadSeeker) (string, error) {
+	if !fi.ModTime().IsZero() {
+		return fmt.Sprintf(`"%x-%x"`, fi.Size(), fi.ModTime().UnixNano()), nil
+	}
+	if etag, ok := h.etags.Load(name); ok {
+		return etag.(string), nil
+	}
+	hash := fnv.New64a()
+	if _, err := io.Copy(hash, rs); err != nil {
+		return "", err
+	}
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	etag := fmt.Sprintf(`"%x-%x"`, fi.Size(), hash.Sum64())
+	h.etags.Store(name, etag)
+	return etag, nil
+}
@@ -1,83 +1,225 @@
 package server
 
 import (
-	"log"
+	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 )
 
+type nodeType uint8
+
 const (
-	pathParamX = "[+-*/]"
+	nodeStatic nodeType = iota
+	nodeParam
+	nodeCatchAll
 )
 
+// routeOptions 路由注册时的配置
+type routeOptions struct {
+	middlewares int   // group中间件数量
+	bodyLimit   int64 // 0:使用engine配置 <0:不限制
+	streamBody  bool  // 不预读body
+}
+
 type node struct {
-	path     string
-	name     string
-	children map[string]*node
-	isRoot   bool
-	cores    []core
+	path      string
+	nType     nodeType
+	children  map[string]*node
+	paramNode *node
+	catchNode *node
+	// 路由注册信息, 只有叶子节点有值
+	fullPath   string
+	paramNames []string
+	opts       routeOptions
+	cores      []core
 }
 
 type trees map[string]*node
 
 func newTree() trees {
-	return map[string]*node{
-		http.MethodPost:   nil,
-		http.MethodGet:    nil,
-		http.MethodDelete: nil,
-		http.MethodPatch:  nil,
-		http.MethodPut:    nil,
+	return make(map[string]*node)
+}
+
+func splitPath(path string) []string {
+	ss := strings.Split(path, "/")
+	ns := make([]string, 0, len(ss))
+	for _, s := range ss {
+		if s != "" {
+			ns = append(ns, s)
+		}
 	}
+	return ns
+}
+
+func isParamSegment(s string) bool {
+	return len(s) > 2 && s[0] == '{' && s[len(s)-1] == '}'
 }
 
-func (t trees) addRoute(method string, path string, cores ...core) {
+func (t trees) addRoute(method string, path string, opts routeOptions, cores ...core) {
 	if len(path) == 0 {
 		panic("path length is zero")
 	}
-	if t[method] == nil {
-		t[method] = &node{
-			isRoot:   true,
+	if path[0] != '/' {
+		panic(fmt.Sprintf("path must begin with '/', path:%s", path))
+	}
+	root := t[method]
+	if root == nil {
+		root = &node{
+			path:     "/",
+			nType:    nodeStatic,
 			children: make(map[string]*node),
 		}
+		t[method] = root
 	}
-	if len(path) == 1 && path[0] == '/' {
-		t[method].cores = append(t[method].cores, cores...)
-	} else {
-		ss := strings.Split(path, "/")
-		var ns []string
-		for _, s := range ss {
-			if s != "" {
-				ns = append(ns, s)
+	var (
+		names   []string
+		current = root
+		segs    = splitPath(path)
+	)
+	for idx, s := range segs {
+		switch {
+		case s[0] == '*':
+			// catch-all只允许出现在路径最后
+			if idx != len(segs)-1 {
+				panic(fmt.Sprintf("catch-all segment must be at the end of path, path:%s", path))
 			}
-		}
-		current := t[method]
-		for _, s := range ns {
-			if len(s) > 2 && s[0] == '{' && s[len(s)-1] == '}' {
-				p := pathParamX
-				name := s[1 : len(s)-1]
-				if _, has := current.children[p]; has || len(ns) == 1 {
-					log.Print("addRoute path error, path:", path)
-					return
+			if len(s) == 1 {
+				panic(fmt.Sprintf("catch-all segment must have a name, path:%s", path))
+			}
+			if current.catchNode == nil {
+				current.catchNode = &node{
+					path:     s,
+					nType:    nodeCatchAll,
+					children: make(map[string]*node),
 				}
-				current.children[p] = &node{
-					path:     p,
-					name:     name,
-					isRoot:   false,
+			}
+			current = current.catchNode
+			names = append(names, s[1:])
+		case isParamSegment(s):
+			if current.paramNode == nil {
+				current.paramNode = &node{
+					path:     s,
+					nType:    nodeParam,
 					children: make(map[string]*node),
 				}
-				current = current.children[p]
-			} else {
-				if _, has := current.children[s]; !has {
-					current.children[s] = &node{
-						path:     s,
-						name:     s,
-						isRoot:   false,
-						children: make(map[string]*node),
-					}
+			}
+			current = current.paramNode
+			names = append(names, s[1:len(s)-1])
+		default:
+			if strings.ContainsAny(s, "{}") {
+				panic(fmt.Sprintf("invalid path segment %q, path:%s", s, path))
+			}
+			if _, has := current.children[s]; !has {
+				current.children[s] = &node{
+					path:     s,
+					nType:    nodeStatic,
+					children: make(map[string]*node),
 				}
-				current = current.children[s]
 			}
+			current = current.children[s]
 		}
-		current.cores = append(current.cores, cores...)
 	}
+	for i := 0; i < len(names); i++ {
+		for j := i + 1; j < len(names); j++ {
+			if names[i] == names[j] {
+				panic(fmt.Sprintf("duplicate path param %q, path:%s", names[i], path))
+			}
+		}
+	}
+	if len(current.cores) > 0 && !equalNames(current.paramNames, names) {
+		panic(fmt.Sprintf("path %s conflicts with existing route %s", path, current.fullPath))
+	}
+	if len(current.cores) == 0 {
+		current.opts = opts
+	}
+	current.fullPath = path
+	current.paramNames = names
+	current.cores = append(current.cores, cores...)
+}
+
+// getValue 按 静态 > 参数 > catch-all 的优先级匹配, 匹配失败时回溯
+func (n *node) getValue(segs []string, values []string) (*node, []string) {
+	if len(segs) == 0 {
+		if len(n.cores) > 0 {
+			return n, values
+		}
+		if n.catchNode != nil && len(n.catchNode.cores) > 0 {
+			return n.catchNode, append(values, "")
+		}
+		return nil, values
+	}
+	if child, has := n.children[segs[0]]; has {
+		if leaf, vs := child.getValue(segs[1:], values); leaf != nil {
+			return leaf, vs
+		}
+	}
+	if n.paramNode != nil {
+		if leaf, vs := n.paramNode.getValue(segs[1:], append(values, segs[0])); leaf != nil {
+			return leaf, vs
+		}
+	}
+	if n.catchNode != nil && len(n.catchNode.cores) > 0 {
+		return n.catchNode, append(values, strings.Join(segs, "/"))
+	}
+	return nil, values
+}
+
+func (t trees) find(method string, segs []string) (*node, []string) {
+	root := t[method]
+	if root == nil {
+		return nil, nil
+	}
+	return root.getValue(segs, nil)
+}
+
+// allowed 返回该路径上已注册的method, 无匹配时返回nil
+func (t trees) allowed(segs []string) []string {
+	var methods []string
+	for m, root := range t {
+		if root == nil {
+			continue
+		}
+		if leaf, _ := root.getValue(segs, nil); leaf != nil {
+			methods = append(methods, m)
+		}
+	}
+	if len(methods) == 0 {
+		return nil
+	}
+	// HEAD由GET自动应答, OPTIONS由框架自动应答
+	if containsMethod(methods, http.MethodGet) && !containsMethod(methods, http.MethodHead) {
+		methods = append(methods, http.MethodHead)
+	}
+	if !containsMethod(methods, http.MethodOptions) {
+		methods = append(methods, http.MethodOptions)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+func containsMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+func equalNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func methodNotAllowed(c *Context, allow []string) {
+	c.Writer().Header().Set("Allow", strings.Join(allow, ", "))
+	_ = c.HttpError(http.StatusMethodNotAllowed, "method not allowed!")
 }
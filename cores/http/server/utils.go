@@ -1,6 +1,10 @@
 package server
 
-import "path"
+import (
+	"path"
+	"strconv"
+	"strings"
+)
 
 func lastChar(str string) uint8 {
 	if str == "" {
@@ -20,3 +24,43 @@ func joinPaths(absolutePath, relativePath string) string {
 	}
 	return finalPath
 }
+
+// validMethod method必须为大写字母
+func validMethod(method string) bool {
+	if method == "" {
+		return false
+	}
+	for i := 0; i < len(method); i++ {
+		if method[i] < 'A' || method[i] > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// acceptEncodings 解析Accept-Encoding, 返回q>0的编码及其权重
+func acceptEncodings(header string) map[string]float64 {
+	encs := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if params != "" {
+			k, v, _ := strings.Cut(strings.TrimSpace(params), "=")
+			if strings.TrimSpace(k) == "q" {
+				f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+				if err != nil {
+					continue
+				}
+				q = f
+			}
+		}
+		if q > 0 {
+			encs[strings.ToLower(strings.TrimSpace(name))] = q
+		}
+	}
+	return encs
+}
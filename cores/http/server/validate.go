@@ -0,0 +1,426 @@
+package server
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	gErrors "github.com/wangshanqi84-gif/sagittarius/cores/errors"
+)
+
+const (
+	validateTag = "validate"
+)
+
+// ErrInvalidParam 参数校验失败, 字段信息通过Details获取
+var ErrInvalidParam = gErrors.New(400, "invalid parameter").Lang("zh", "参数错误")
+
+// ValidateFunc 校验函数, field为字段值, param为规则参数, 如min=1中的"1"
+type ValidateFunc func(field reflect.Value, param string) bool
+
+type validateRule struct {
+	tag   string
+	param string
+	fn    ValidateFunc
+}
+
+type validateField struct {
+	index     int
+	name      string
+	rules     []validateRule
+	required  bool
+	omitempty bool
+}
+
+var (
+	validators = map[string]ValidateFunc{
+		"min":      validateMin,
+		"max":      validateMax,
+		"len":      validateLen,
+		"eq":       validateEq,
+		"ne":       validateNe,
+		"gt":       validateGt,
+		"gte":      validateMin,
+		"lt":       validateLt,
+		"lte":      validateMax,
+		"oneof":    validateOneOf,
+		"email":    validateEmail,
+		"url":      validateURL,
+		"numeric":  validateNumeric,
+		"alpha":    validateAlpha,
+		"alphanum": validateAlphaNum,
+	}
+	// validateMessages 错误提示, 参数依次为字段名和规则参数
+	// 字符串/数组类的长度校验优先使用"tag.len"对应的提示
+	validateMessages = map[string]*gErrors.Error{
+		"required": gErrors.New(400, "%s is required").Lang("zh", "%s为必填字段"),
+		"min":      gErrors.New(400, "%s must be greater than or equal to %s").Lang("zh", "%s必须大于或等于%s"),
+		"min.len":  gErrors.New(400, "%s must be at least %s in length").Lang("zh", "%s长度必须至少为%s"),
+		"max":      gErrors.New(400, "%s must be less than or equal to %s").Lang("zh", "%s必须小于或等于%s"),
+		"max.len":  gErrors.New(400, "%s must be at most %s in length").Lang("zh", "%s长度不能超过%s"),
+		"len":      gErrors.New(400, "%s must be equal to %s").Lang("zh", "%s必须等于%s"),
+		"len.len":  gErrors.New(400, "%s must be %s in length").Lang("zh", "%s长度必须为%s"),
+		"eq":       gErrors.New(400, "%s must be equal to %s").Lang("zh", "%s必须等于%s"),
+		"ne":       gErrors.New(400, "%s must not be equal to %s").Lang("zh", "%s不能等于%s"),
+		"gt":       gErrors.New(400, "%s must be greater than %s").Lang("zh", "%s必须大于%s"),
+		"gt.len":   gErrors.New(400, "%s must be more than %s in length").Lang("zh", "%s长度必须大于%s"),
+		"gte":      gErrors.New(400, "%s must be greater than or equal to %s").Lang("zh", "%s必须大于或等于%s"),
+		"gte.len":  gErrors.New(400, "%s must be at least %s in length").Lang("zh", "%s长度必须至少为%s"),
+		"lt":       gErrors.New(400, "%s must be less than %s").Lang("zh", "%s必须小于%s"),
+		"lt.len":   gErrors.New(400, "%s must be less than %s in length").Lang("zh", "%s长度必须小于%s"),
+		"lte":      gErrors.New(400, "%s must be less than or equal to %s").Lang("zh", "%s必须小于或等于%s"),
+		"lte.len":  gErrors.New(400, "%s must be at most %s in length").Lang("zh", "%s长度不能超过%s"),
+		"oneof":    gErrors.New(400, "%s must be one of [%s]").Lang("zh", "%s必须是[%s]中的一个"),
+		"email":    gErrors.New(400, "%s must be a valid email address").Lang("zh", "%s必须是一个有效的邮箱"),
+		"url":      gErrors.New(400, "%s must be a valid URL").Lang("zh", "%s必须是一个有效的URL"),
+		"numeric":  gErrors.New(400, "%s must be a valid numeric value").Lang("zh", "%s必须是一个有效的数值"),
+		"alpha":    gErrors.New(400, "%s can only contain alphabetic characters").Lang("zh", "%s只能包含字母"),
+		"alphanum": gErrors.New(400, "%s can only contain alphanumeric characters").Lang("zh", "%s只能包含字母和数字"),
+		"default":  gErrors.New(400, "%s failed on the '%s' rule").Lang("zh", "%s未通过'%s'校验"),
+	}
+	validateCache sync.Map
+	validateMu    sync.RWMutex
+)
+
+// RegisterValidation 注册自定义校验规则, 需在服务启动前调用
+func RegisterValidation(tag string, fn ValidateFunc, message *gErrors.Error) {
+	validateMu.Lock()
+	defer validateMu.Unlock()
+
+	validators[tag] = fn
+	if message != nil {
+		validateMessages[tag] = message
+	}
+	validateCache.Range(func(key, _ interface{}) bool {
+		validateCache.Delete(key)
+		return true
+	})
+}
+
+// Validate 按validate标签校验结构体, 校验失败返回ErrInvalidParam
+func Validate(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	var ge *gErrors.Error
+	validateStruct(rv, "", &ge)
+	if ge != nil {
+		return ge
+	}
+	return nil
+}
+
+func validateStruct(rv reflect.Value, prefix string, ge **gErrors.Error) {
+	for _, f := range structRules(rv.Type()) {
+		validateValue(rv.Field(f.index), prefix+f.name, f, ge)
+	}
+}
+
+func validateValue(fv reflect.Value, name string, f *validateField, ge **gErrors.Error) {
+	if isZero(fv) {
+		if f.required {
+			addValidateErr(ge, name, "required", "", fv)
+		}
+		if f.required || f.omitempty || fv.Kind() == reflect.Ptr || fv.Kind() == reflect.Interface {
+			return
+		}
+	}
+	for fv.Kind() == reflect.Ptr || fv.Kind() == reflect.Interface {
+		if fv.IsNil() {
+			return
+		}
+		fv = fv.Elem()
+	}
+	for _, r := range f.rules {
+		if !r.fn(fv, r.param) {
+			addValidateErr(ge, name, r.tag, r.param, fv)
+			return
+		}
+	}
+	switch fv.Kind() {
+	case reflect.Struct:
+		validateStruct(fv, name+".", ge)
+	case reflect.Slice, reflect.Array:
+		if !isStructElem(fv.Type()) {
+			return
+		}
+		// 结构体数组逐个校验元素
+		elem := &validateField{}
+		for i := 0; i < fv.Len(); i++ {
+			validateValue(fv.Index(i), fmt.Sprintf("%s[%d]", name, i), elem, ge)
+		}
+	}
+}
+
+func addValidateErr(ge **gErrors.Error, field string, tag string, param string, fv reflect.Value) {
+	validateMu.RLock()
+	defer validateMu.RUnlock()
+
+	msg, has := validateMessages[tag+".len"]
+	if !has || !hasLength(fv) {
+		msg, has = validateMessages[tag]
+	}
+	if !has {
+		msg, param = validateMessages["default"], tag
+	}
+	args := []interface{}{field}
+	if param != "" {
+		args = append(args, strings.ReplaceAll(param, " ", ", "))
+	}
+	if *ge == nil {
+		*ge = ErrInvalidParam
+	}
+	*ge = (*ge).WithDetail(field, msg, args...)
+}
+
+func structRules(t reflect.Type) []*validateField {
+	if fs, ok := validateCache.Load(t); ok {
+		return fs.([]*validateField)
+	}
+	validateMu.RLock()
+	defer validateMu.RUnlock()
+
+	var fs []*validateField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		tag := sf.Tag.Get(validateTag)
+		if tag == "-" {
+			continue
+		}
+		ft := sf.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if tag == "" && ft.Kind() != reflect.Struct && !isStructElem(ft) {
+			continue
+		}
+		f := &validateField{
+			index: i,
+			name:  fieldName(sf),
+		}
+		for _, item := range strings.Split(tag, ",") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			name, param, _ := strings.Cut(item, "=")
+			switch name {
+			case "required":
+				f.required = true
+			case "omitempty":
+				f.omitempty = true
+			default:
+				fn, has := validators[name]
+				if !has {
+					panic(fmt.Sprintf("undefined validation %q on %s.%s", name, t.Name(), sf.Name))
+				}
+				f.rules = append(f.rules, validateRule{tag: name, param: param, fn: fn})
+			}
+		}
+		fs = append(fs, f)
+	}
+	validateCache.Store(t, fs)
+	return fs
+}
+
+func fieldName(sf reflect.StructField) string {
+	name := strings.Split(sf.Tag.Get("json"), ",")[0]
+	if name == "" || name == "-" {
+		return sf.Name
+	}
+	return name
+}
+
+func isStructElem(t reflect.Type) bool {
+	if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+		return false
+	}
+	et := t.Elem()
+	for et.Kind() == reflect.Ptr {
+		et = et.Elem()
+	}
+	return et.Kind() == reflect.Struct
+}
+
+func isZero(fv reflect.Value) bool {
+	switch fv.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map, reflect.Chan, reflect.Func:
+		return fv.IsNil()
+	}
+	return fv.IsZero()
+}
+
+func hasLength(fv reflect.Value) bool {
+	switch fv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return true
+	}
+	return false
+}
+
+// compareParam 比较字段与参数, 字符串/数组类比较长度
+func compareParam(fv reflect.Value, param string) (int, bool) {
+	switch fv.Kind() {
+	case reflect.String:
+		return compareInt(int64(utf8.RuneCountInString(fv.String())), param)
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return compareInt(int64(fv.Len()), param)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return compareInt(fv.Int(), param)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		p, err := strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		return compareOrdered(fv.Uint(), p), true
+	case reflect.Float32, reflect.Float64:
+		p, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return 0, false
+		}
+		return compareOrdered(fv.Float(), p), true
+	}
+	return 0, false
+}
+
+func compareInt(v int64, param string) (int, bool) {
+	p, err := strconv.ParseInt(param, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return compareOrdered(v, p), true
+}
+
+func compareOrdered[T int64 | uint64 | float64](a, b T) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
+
+func validateMin(fv reflect.Value, param string) bool {
+	r, ok := compareParam(fv, param)
+	return ok && r >= 0
+}
+
+func validateMax(fv reflect.Value, param string) bool {
+	r, ok := compareParam(fv, param)
+	return ok && r <= 0
+}
+
+func validateLen(fv reflect.Value, param string) bool {
+	r, ok := compareParam(fv, param)
+	return ok && r == 0
+}
+
+func validateGt(fv reflect.Value, param string) bool {
+	r, ok := compareParam(fv, param)
+	return ok && r > 0
+}
+
+func validateLt(fv reflect.Value, param string) bool {
+	r, ok := compareParam(fv, param)
+	return ok && r < 0
+}
+
+func validateEq(fv reflect.Value, param string) bool {
+	if fv.Kind() == reflect.String {
+		return fv.String() == param
+	}
+	return validateLen(fv, param)
+}
+
+func validateNe(fv reflect.Value, param string) bool {
+	return !validateEq(fv, param)
+}
+
+func validateOneOf(fv reflect.Value, param string) bool {
+	var s string
+	switch fv.Kind() {
+	case reflect.String:
+		s = fv.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s = strconv.FormatInt(fv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s = strconv.FormatUint(fv.Uint(), 10)
+	default:
+		return false
+	}
+	for _, p := range strings.Fields(param) {
+		if p == s {
+			return true
+		}
+	}
+	return false
+}
+
+func validateEmail(fv reflect.Value, _ string) bool {
+	if fv.Kind() != reflect.String {
+		return false
+	}
+	addr, err := mail.ParseAddress(fv.String())
+	return err == nil && addr.Address == fv.String()
+}
+
+func validateURL(fv reflect.Value, _ string) bool {
+	if fv.Kind() != reflect.String {
+		return false
+	}
+	u, err := url.Parse(fv.String())
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+func validateNumeric(fv reflect.Value, _ string) bool {
+	switch fv.Kind() {
+	case reflect.String:
+		_, err := strconv.ParseFloat(fv.String(), 64)
+		return err == nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func validateAlpha(fv reflect.Value, _ string) bool {
+	if fv.Kind() != reflect.String {
+		return false
+	}
+	for _, r := range fv.String() {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+func validateAlphaNum(fv reflect.Value, _ string) bool {
+	if fv.Kind() != reflect.String {
+		return false
+	}
+	for _, r := range fv.String() {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,194 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultTTL     = 24 * time.Hour
+	defaultLockTTL = time.Minute
+
+	maxKeyLength = 255
+)
+
+var (
+	// ErrInProgress 相同key的请求仍在处理中
+	ErrInProgress = errors.New("request with the same idempotency key is in progress")
+	// ErrMismatch 相同key对应的请求内容不一致
+	ErrMismatch    = errors.New("idempotency key reused with different request")
+	ErrInvalidKey  = errors.New("invalid idempotency key")
+	ErrKeyRequired = errors.New("idempotency key required")
+)
+
+// Record 请求处理结果, http为状态码/header/body, grpc为code/message/序列化后的reply
+type Record struct {
+	Pending     bool                `json:"pending,omitempty"`
+	Token       string              `json:"token,omitempty"`
+	Fingerprint string              `json:"fingerprint"`
+	Status      int                 `json:"status,omitempty"`
+	Message     string              `json:"message,omitempty"`
+	Header      map[string][]string `json:"header,omitempty"`
+	Body        []byte              `json:"body,omitempty"`
+}
+
+// Store 幂等记录存储
+type Store interface {
+	// Acquire key不存在时写入pending记录并返回nil, 否则返回已有记录
+	Acquire(ctx context.Context, key string, pending *Record, lockTTL time.Duration) (*Record, error)
+	// Complete 保存处理结果, 仅当key仍属于token或已过期时写入
+	Complete(ctx context.Context, key, token string, rec *Record, ttl time.Duration) error
+	// Release 删除token持有的pending记录, 之后相同key可重新执行
+	Release(ctx context.Context, key, token string) error
+}
+
+type Option func(*options)
+
+type options struct {
+	ttl      time.Duration
+	lockTTL  time.Duration
+	required bool
+	methods  []string
+}
+
+// TTL 处理结果保存时间, 默认24小时
+func TTL(d time.Duration) Option {
+	return func(o *options) {
+		o.ttl = d
+	}
+}
+
+// LockTTL 处理中状态的最长保持时间, 进程异常退出后到期自动释放, 默认1分钟
+func LockTTL(d time.Duration) Option {
+	return func(o *options) {
+		o.lockTTL = d
+	}
+}
+
+// Required 未携带key时拒绝请求
+func Required() Option {
+	return func(o *options) {
+		o.required = true
+	}
+}
+
+// Methods 需要幂等处理的http method, 默认POST和PATCH, grpc忽略该配置
+func Methods(methods ...string) Option {
+	return func(o *options) {
+		o.methods = methods
+	}
+}
+
+// Guard 基于Store的幂等控制, 由http/grpc中间件使用
+type Guard struct {
+	store Store
+	opts  options
+}
+
+func New(store Store, opts ...Option) *Guard {
+	o := options{
+		ttl:     defaultTTL,
+		lockTTL: defaultLockTTL,
+		methods: []string{"POST", "PATCH"},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Guard{
+		store: store,
+		opts:  o,
+	}
+}
+
+// Required 未携带key时是否拒绝请求
+func (g *Guard) Required() bool {
+	return g.opts.required
+}
+
+// Match http method是否需要幂等处理
+func (g *Guard) Match(method string) bool {
+	for _, m := range g.opts.methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// Entry 抢占成功的key, 处理结束后必须调用Complete或Release
+type Entry struct {
+	g     *Guard
+	key   string
+	token string
+	fp    string
+}
+
+// Begin 开始处理key, key需先经ValidKey校验
+// 返回Entry表示需要执行业务; 返回Record表示已有结果可直接重放
+// 处理中返回ErrInProgress, 请求内容不一致返回ErrMismatch
+func (g *Guard) Begin(ctx context.Context, key, fingerprint string) (*Entry, *Record, error) {
+	token := uuid.NewString()
+	rec, err := g.store.Acquire(ctx, key, &Record{
+		Pending:     true,
+		Token:       token,
+		Fingerprint: fingerprint,
+	}, g.opts.lockTTL)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rec == nil {
+		return &Entry{g: g, key: key, token: token, fp: fingerprint}, nil, nil
+	}
+	if rec.Fingerprint != fingerprint {
+		return nil, nil, ErrMismatch
+	}
+	if rec.Pending {
+		return nil, nil, ErrInProgress
+	}
+	return nil, rec, nil
+}
+
+// Complete 保存结果, 之后相同key的请求直接返回该结果
+func (e *Entry) Complete(ctx context.Context, rec *Record) error {
+	rec.Pending = false
+	rec.Token = ""
+	rec.Fingerprint = e.fp
+	return e.g.store.Complete(ctx, e.key, e.token, rec, e.g.opts.ttl)
+}
+
+// Release 放弃本次结果, 用于处理失败后允许客户端重试
+func (e *Entry) Release(ctx context.Context) error {
+	return e.g.store.Release(ctx, e.key, e.token)
+}
+
+// ValidKey key长度不超过255且只包含可见ASCII字符
+func ValidKey(key string) bool {
+	if key == "" || len(key) > maxKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x21 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// Fingerprint 请求摘要, 用于识别相同key对应不同请求的误用
+func Fingerprint(parts ...[]byte) string {
+	h := sha256.New()
+	for _, p := range parts {
+		// 写入长度避免拼接歧义
+		var n [8]byte
+		binary.BigEndian.PutUint64(n[:], uint64(len(p)))
+		h.Write(n[:])
+		h.Write(p)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
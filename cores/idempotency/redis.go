@@ -0,0 +1,99 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	redisgo "github.com/go-redis/redis/v8"
+)
+
+// acquireScript 不存在时写入pending记录, 已存在时返回已有记录
+// KEYS[1] key ARGV[1] pending记录 ARGV[2] 过期时间(毫秒)
+var acquireScript = redisgo.NewScript(`
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+	return false
+end
+return redis.call('GET', KEYS[1])
+`)
+
+// completeScript key仍属于token或已过期时写入结果
+// KEYS[1] key ARGV[1] token ARGV[2] 结果 ARGV[3] 过期时间(毫秒)
+var completeScript = redisgo.NewScript(`
+local v = redis.call('GET', KEYS[1])
+if v then
+	local ok, r = pcall(cjson.decode, v)
+	if not ok or r.token ~= ARGV[1] then
+		return 0
+	end
+end
+redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+return 1
+`)
+
+// releaseScript 删除token持有的pending记录
+// KEYS[1] key ARGV[1] token
+var releaseScript = redisgo.NewScript(`
+local v = redis.call('GET', KEYS[1])
+if not v then
+	return 0
+end
+local ok, r = pcall(cjson.decode, v)
+if not ok or r.token ~= ARGV[1] then
+	return 0
+end
+return redis.call('DEL', KEYS[1])
+`)
+
+// RedisStore 基于redis的幂等记录存储, 多实例共享
+type RedisStore struct {
+	cmd    redisgo.Scripter
+	prefix string
+}
+
+// NewRedisStore prefix为key前缀, 不同服务共用redis时应区分
+func NewRedisStore(cmd redisgo.Scripter, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = "idempotency"
+	}
+	return &RedisStore{
+		cmd:    cmd,
+		prefix: prefix,
+	}
+}
+
+func (s *RedisStore) key(key string) string {
+	return fmt.Sprintf("%s:{%s}", s.prefix, key)
+}
+
+func (s *RedisStore) Acquire(ctx context.Context, key string, pending *Record, lockTTL time.Duration) (*Record, error) {
+	bs, err := json.Marshal(pending)
+	if err != nil {
+		return nil, err
+	}
+	v, err := acquireScript.Run(ctx, s.cmd, []string{s.key(key)}, string(bs), lockTTL.Milliseconds()).Text()
+	if err == redisgo.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var rec Record
+	if err = json.Unmarshal([]byte(v), &rec); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+func (s *RedisStore) Complete(ctx context.Context, key, token string, rec *Record, ttl time.Duration) error {
+	bs, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return completeScript.Run(ctx, s.cmd, []string{s.key(key)}, token, string(bs), ttl.Milliseconds()).Err()
+}
+
+func (s *RedisStore) Release(ctx context.Context, key, token string) error {
+	return releaseScript.Run(ctx, s.cmd, []string{s.key(key)}, token).Err()
+}
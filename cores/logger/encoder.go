@@ -7,6 +7,8 @@ import (
 	"strings"
 	"time"
 
+	gCtx "github.com/wangshanqi84-gif/sagittarius/cores/context"
+
 	"github.com/opentracing/opentracing-go"
 	"github.com/uber/jaeger-client-go"
 )
@@ -51,3 +53,7 @@ func traceEncoder(ctx context.Context) string {
 	}
 	return traceID
 }
+
+func requestIDEncoder(ctx context.Context) string {
+	return gCtx.FromRequestIDContext(ctx)
+}
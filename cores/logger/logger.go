@@ -190,14 +190,17 @@ func (l *Logger) write(ctx context.Context, level Level, format string, args ...
 		if traceID := traceEncoder(ctx); traceID != "" {
 			data["trace_id"] = traceID
 		}
-		bs, err := json.Marshal(data)
-		if err != nil {
-			return
+		if requestID := requestIDEncoder(ctx); requestID != "" {
+			data["request_id"] = requestID
 		}
 		for _, ce := range l.EncoderCustom {
 			k, v := ce(ctx)
 			data[k] = v
 		}
+		bs, err := json.Marshal(data)
+		if err != nil {
+			return
+		}
 		buf.Write(bs)
 	case ConsoleFormat:
 		if d := l.EncodeTime(time.Now()); d != "" {
@@ -219,6 +222,9 @@ func (l *Logger) write(ctx context.Context, level Level, format string, args ...
 		if traceID := traceEncoder(ctx); traceID != "" {
 			buf.WriteString("(" + traceID + ")")
 		}
+		if requestID := requestIDEncoder(ctx); requestID != "" {
+			buf.WriteString("<" + requestID + ">")
+		}
 	}
 	if buf.Len() > 0 {
 		buf.WriteString("\n")
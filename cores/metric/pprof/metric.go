@@ -33,14 +33,28 @@ func SetPort(port int) Option {
 var _m *Metric
 var _once sync.Once
 
+var (
+	// profiles runtime/pprof内置profile
+	profiles = []string{"allocs", "heap", "goroutine", "block", "mutex", "threadcreate"}
+)
+
+// Register 在mux上注册全部pprof handler
+// block/mutex需要通过runtime.SetBlockProfileRate/SetMutexProfileFraction开启采样
+func Register(mux *http.ServeMux) {
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	for _, name := range profiles {
+		mux.Handle("/debug/pprof/"+name, pprof.Handler(name))
+	}
+}
+
 func InitMetric(ctx context.Context, opts ...Option) *Metric {
 	_once.Do(func() {
 		mux := http.NewServeMux()
-		mux.HandleFunc("/debug/pprof/", pprof.Index)
-		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
-		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
-		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
-		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		Register(mux)
 
 		_m = &Metric{
 			ctx:  ctx,
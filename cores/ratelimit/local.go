@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// bucketIdle 空闲超过该时间的桶会被清理
+	bucketIdle = 10 * time.Minute
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// TokenBucket 进程内令牌桶, 每个key独立计数
+type TokenBucket struct {
+	mu        sync.Mutex
+	rate      float64
+	burst     int
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewTokenBucket rate为每秒补充的令牌数, burst为桶容量, burst<=0时取rate
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if burst <= 0 {
+		burst = int(math.Ceil(rate))
+	}
+	return &TokenBucket{
+		rate:      rate,
+		burst:     burst,
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+func (tb *TokenBucket) Allow(_ context.Context, key string) (Result, error) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	now := time.Now()
+	tb.sweep(now)
+	b, has := tb.buckets[key]
+	if !has {
+		b = &bucket{tokens: float64(tb.burst), last: now}
+		tb.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(tb.burst), b.tokens+now.Sub(b.last).Seconds()*tb.rate)
+	b.last = now
+
+	res := Result{Limit: tb.burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+		res.Remaining = int(b.tokens)
+		return res, nil
+	}
+	if tb.rate > 0 {
+		res.RetryAfter = time.Duration((1 - b.tokens) / tb.rate * float64(time.Second))
+	}
+	return res, nil
+}
+
+// sweep 定期清理长时间未使用的桶, 避免key过多时内存增长
+func (tb *TokenBucket) sweep(now time.Time) {
+	if now.Sub(tb.lastSweep) < bucketIdle {
+		return
+	}
+	tb.lastSweep = now
+	for k, b := range tb.buckets {
+		if now.Sub(b.last) >= bucketIdle {
+			delete(tb.buckets, k)
+		}
+	}
+}
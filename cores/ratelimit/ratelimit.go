@@ -0,0 +1,28 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	TypeLocal = "local" // 进程内令牌桶
+	TypeRedis = "redis" // redis滑动窗口
+
+	KeyIP      = "ip"      // 按客户端ip限流
+	KeyRoute   = "route"   // 按路由/方法/消息ID限流
+	KeyService = "service" // 按上游服务限流
+	KeyGlobal  = "global"  // 全局共用一个限额
+)
+
+// Result 单次限流判定结果
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+type Limiter interface {
+	Allow(ctx context.Context, key string) (Result, error)
+}
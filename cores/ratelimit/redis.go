@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	redisgo "github.com/go-redis/redis/v8"
+)
+
+// slidingWindowScript 基于zset的滑动窗口, 使用redis服务端时间避免实例间时钟偏差
+// KEYS[1] 计数key
+// ARGV[1] 窗口大小(毫秒) ARGV[2] 限额 ARGV[3] 随机成员后缀
+// 返回 {是否允许, 剩余次数, 重试等待毫秒}
+var slidingWindowScript = redisgo.NewScript(`
+local key = KEYS[1]
+local window = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+redis.call('ZREMRANGEBYSCORE', key, 0, now - window)
+local count = redis.call('ZCARD', key)
+if count < limit then
+	redis.call('ZADD', key, now, now .. '-' .. ARGV[3])
+	redis.call('PEXPIRE', key, window)
+	return {1, limit - count - 1, 0}
+end
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+local retry = window
+if oldest[2] then
+	retry = tonumber(oldest[2]) + window - now
+end
+return {0, 0, retry}
+`)
+
+// SlidingWindow redis分布式滑动窗口, 同一key在所有实例间共享限额
+type SlidingWindow struct {
+	cmd    redisgo.Scripter
+	prefix string
+	limit  int
+	window time.Duration
+}
+
+// NewSlidingWindow window内最多允许limit次请求, prefix为key前缀
+func NewSlidingWindow(cmd redisgo.Scripter, prefix string, limit int, window time.Duration) *SlidingWindow {
+	if prefix == "" {
+		prefix = "ratelimit"
+	}
+	return &SlidingWindow{
+		cmd:    cmd,
+		prefix: prefix,
+		limit:  limit,
+		window: window,
+	}
+}
+
+// key 使用hash tag保证集群模式下同一限流key落在同一slot
+func (sw *SlidingWindow) key(key string) string {
+	return fmt.Sprintf("%s:{%s}", sw.prefix, key)
+}
+
+func (sw *SlidingWindow) Allow(ctx context.Context, key string) (Result, error) {
+	res := Result{Limit: sw.limit}
+	vs, err := slidingWindowScript.Run(ctx, sw.cmd, []string{sw.key(key)},
+		sw.window.Milliseconds(), sw.limit, rand.Int63()).Int64Slice()
+	if err != nil {
+		return res, err
+	}
+	if len(vs) != 3 {
+		return res, fmt.Errorf("unexpected sliding window result:%v", vs)
+	}
+	res.Allowed = vs[0] == 1
+	res.Remaining = int(vs[1])
+	res.RetryAfter = time.Duration(vs[2]) * time.Millisecond
+	return res, nil
+}
@@ -57,17 +57,20 @@ func (r *Registry) Register(_ context.Context, srv *registry.Service) error {
 		if err != nil {
 			return err
 		}
+		meta := make(map[string]string)
+		for k, v := range srv.Metadata {
+			meta[k] = v
+		}
+		meta["namespace"] = srv.Namespace
+		meta["product"] = srv.Product
+		meta["serviceName"] = srv.ServiceName
 		asr := &api.AgentServiceRegistration{
 			ID:      fmt.Sprintf("%s-%s", srv.ID, proto),
 			Name:    fmt.Sprintf("%s-%s", key, proto),
 			Address: raw.Hostname(),
 			Port:    int(port),
-			Meta: map[string]string{
-				"namespace":   srv.Namespace,
-				"product":     srv.Product,
-				"serviceName": srv.ServiceName,
-			},
-			Tags: strings.Split(srv.Tags, ","),
+			Meta:    meta,
+			Tags:    strings.Split(srv.Tags, ","),
 			Check: &api.AgentServiceCheck{
 				TCP:                            host,
 				Interval:                       fmt.Sprintf("%ds", 10),
@@ -60,6 +60,7 @@ func (w *watcher) Start() ([]*registry.Service, error) {
 					Product:     entry.Service.Meta["product"],
 					ServiceName: entry.Service.Meta["serviceName"],
 					Tags:        strings.Join(entry.Service.Tags, ","),
+					Metadata:    entry.Service.Meta,
 				}
 				if srv.ServiceName != w.serviceName {
 					continue
@@ -61,6 +61,7 @@ func (w *watcher) Start() ([]*registry.Service, error) {
 					Product:     ins.Metadata["product"],
 					ServiceName: ins.Metadata["serviceName"],
 					Tags:        ins.Metadata["tags"],
+					Metadata:    ins.Metadata,
 				}
 				if srv.ServiceName != w.serviceName {
 					continue
@@ -5,6 +5,7 @@ import (
 	"crypto/tls"
 	"fmt"
 
+	"github.com/wangshanqi84-gif/sagittarius/cores/hedge"
 	"github.com/wangshanqi84-gif/sagittarius/cores/registry"
 	"github.com/wangshanqi84-gif/sagittarius/cores/rpc/client/resolver/direct"
 	"github.com/wangshanqi84-gif/sagittarius/cores/rpc/client/resolver/discovery"
@@ -60,6 +61,14 @@ func WithBalancerName(balancerName string) Option {
 	}
 }
 
+// WithHedging 开启对冲请求, 只对cfg.Methods中声明的方法生效, name为下游服务名
+// 负载均衡为round_robin时替换为TriedRoundRobin, 其他负载均衡无法保证对冲请求发往其他节点, 建立连接时返回错误
+func WithHedging(name string, cfg *hedge.Config) Option {
+	return func(o *clientOptions) {
+		o.hedger = hedge.New(name, *cfg)
+	}
+}
+
 type clientOptions struct {
 	eps          []string
 	watcher      registry.Watcher
@@ -67,6 +76,7 @@ type clientOptions struct {
 	ints         []grpc.UnaryClientInterceptor
 	grpcOpts     []grpc.DialOption
 	balancerName string
+	hedger       *hedge.Hedger
 }
 
 func DialContext(ctx context.Context, opts ...Option) (*grpc.ClientConn, error) {
@@ -83,10 +93,23 @@ func dial(ctx context.Context, opts ...Option) (*grpc.ClientConn, error) {
 	if len(options.eps) == 0 && options.watcher == nil {
 		return nil, fmt.Errorf("default endpoints is nil and service discovery is nil")
 	}
+	if options.hedger != nil {
+		switch options.balancerName {
+		case roundrobin.Name, TriedRoundRobin:
+			options.balancerName = TriedRoundRobin
+		default:
+			return nil, fmt.Errorf("hedging requires balancer %s or %s, got %s", roundrobin.Name, TriedRoundRobin, options.balancerName)
+		}
+	}
 	grpcOpts := []grpc.DialOption{
 		grpc.WithDefaultServiceConfig(fmt.Sprintf(`{"loadBalancingConfig": [{"%s":{}}]}`, options.balancerName)),
-		grpc.WithChainUnaryInterceptor(options.ints...),
 	}
+	ints := options.ints
+	if options.hedger != nil {
+		// 对冲放在拦截器链最内层, 每个请求只执行一次外层拦截器
+		ints = append(ints[:len(ints):len(ints)], HedgeClientUnaryInterceptor(options.hedger))
+	}
+	grpcOpts = append(grpcOpts, grpc.WithChainUnaryInterceptor(ints...))
 	if options.tlsCfg != nil {
 		grpcOpts = append(grpcOpts, grpc.WithTransportCredentials(credentials.NewTLS(options.tlsCfg)))
 	} else {
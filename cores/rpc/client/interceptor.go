@@ -5,14 +5,18 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/wangshanqi84-gif/sagittarius/cores/breaker"
 	gCtx "github.com/wangshanqi84-gif/sagittarius/cores/context"
+	"github.com/wangshanqi84-gif/sagittarius/cores/hedge"
 
+	"github.com/google/uuid"
 	"github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/ext"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 ///////////////////////////////////////////
@@ -64,8 +68,23 @@ func TimeoutClientUnaryInterceptor(timeout time.Duration) grpc.UnaryClientInterc
 	}
 }
 
+// RetryClientUnaryInterceptor Unavailable和DeadlineExceeded时重试
+// 开启重试时生成idempotency-key, 所有重试共用, 服务端据此去重
 func RetryClientUnaryInterceptor(maxAttempts int) grpc.UnaryClientInterceptor {
 	return func(ctx context.Context, method string, request, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if maxAttempts > 0 {
+			rpcMD, ok := metadata.FromOutgoingContext(ctx)
+			if !ok {
+				rpcMD = metadata.New(nil)
+			} else {
+				rpcMD = rpcMD.Copy()
+			}
+			md := gCtx.Metadata{MD: rpcMD}
+			if gCtx.GetUberIdempotencyKey(md) == "" {
+				gCtx.SetUberIdempotencyKey(md, uuid.NewString())
+				ctx = metadata.NewOutgoingContext(ctx, md.MD)
+			}
+		}
 		var err error
 		for att := 0; att <= maxAttempts; att++ {
 			err = invoker(ctx, method, request, reply, cc, opts...)
@@ -95,3 +114,116 @@ func LangClientUnaryInterceptor() grpc.UnaryClientInterceptor {
 		return invoker(ctx, method, request, reply, cc, opts...)
 	}
 }
+
+// RequestIDClientUnaryInterceptor 向下游传递请求ID
+func RequestIDClientUnaryInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, request, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		id := gCtx.FromRequestIDContext(ctx)
+		if id == "" {
+			return invoker(ctx, method, request, reply, cc, opts...)
+		}
+		rpcMD, ok := metadata.FromOutgoingContext(ctx)
+		if !ok {
+			rpcMD = metadata.New(nil)
+		} else {
+			rpcMD = rpcMD.Copy()
+		}
+		md := gCtx.Metadata{MD: rpcMD}
+		gCtx.SetUberRequestID(md, id)
+		ctx = metadata.NewOutgoingContext(ctx, md.MD)
+		return invoker(ctx, method, request, reply, cc, opts...)
+	}
+}
+
+// BreakerClientUnaryInterceptor 熔断, scope为service时整个下游共用熔断器, 为method时按grpc方法熔断
+// 熔断器打开时返回breaker.ErrOpen
+func BreakerClientUnaryInterceptor(group *breaker.Group, scope string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, request, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		key := breaker.ScopeService
+		if scope == breaker.ScopeMethod {
+			key = method
+		}
+		b := group.Get(key)
+		if err := b.Allow(); err != nil {
+			return err
+		}
+		start := time.Now()
+		err := invoker(ctx, method, request, reply, cc, opts...)
+		b.Report(breakerFailure(err), time.Since(start))
+		return err
+	}
+}
+
+// breakerFailure 下游故障类错误计入熔断, 业务错误不计入
+func breakerFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch status.Code(err) {
+	case codes.Unknown, codes.DeadlineExceeded, codes.ResourceExhausted,
+		codes.Internal, codes.Unavailable, codes.DataLoss:
+		return true
+	}
+	return false
+}
+
+// HedgeClientUnaryInterceptor 对冲请求, 原请求超过对冲延迟未返回时再次发出请求,
+// 使用最先成功的应答并取消其他请求. 负载均衡需为TriedRoundRobin, 对冲请求才会发往其他节点
+func HedgeClientUnaryInterceptor(h *hedge.Hedger) grpc.UnaryClientInterceptor {
+	type result struct {
+		reply   proto.Message
+		err     error
+		idx     int
+		elapsed time.Duration
+	}
+	return func(ctx context.Context, method string, request, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		msg, ok := reply.(proto.Message)
+		if !ok || !h.AllowMethod(method) {
+			return invoker(ctx, method, request, reply, cc, opts...)
+		}
+		h.Request()
+		// 记录各次请求使用的节点, 返回时取消未完成的请求
+		ctx, cancel := context.WithCancel(newTriedContext(ctx))
+		defer cancel()
+		results := make(chan result, h.MaxHedges()+1)
+		send := func(idx int) {
+			r := msg.ProtoReflect().New().Interface()
+			go func() {
+				start := time.Now()
+				err := invoker(ctx, method, request, r, cc, opts...)
+				results <- result{reply: r, err: err, idx: idx, elapsed: time.Since(start)}
+			}()
+		}
+		send(0)
+		timer := time.NewTimer(h.Delay())
+		defer timer.Stop()
+		sent, pending := 1, 1
+		for {
+			select {
+			case r := <-results:
+				pending--
+				if r.err == nil {
+					h.Observe(r.elapsed)
+					if r.idx > 0 {
+						h.Won()
+					}
+					proto.Reset(msg)
+					proto.Merge(msg, r.reply)
+					return nil
+				}
+				// 失败时等待其他请求, 全部失败时返回最后的错误
+				if pending == 0 {
+					return r.err
+				}
+			case <-timer.C:
+				if sent > h.MaxHedges() || !h.Allow() {
+					continue
+				}
+				send(sent)
+				sent++
+				pending++
+				timer.Reset(h.Delay())
+			}
+		}
+	}
+}
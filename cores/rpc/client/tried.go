@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+)
+
+// TriedRoundRobin 轮询负载均衡, 同一请求的对冲请求优先选择未请求过的节点
+// 开启对冲时默认使用
+const TriedRoundRobin = "tried_round_robin"
+
+func init() {
+	balancer.Register(base.NewBalancerBuilder(TriedRoundRobin, &triedPickerBuilder{}, base.Config{HealthCheck: true}))
+}
+
+type triedKey struct{}
+
+// triedNodes 同一请求各次对冲已请求过的节点
+type triedNodes struct {
+	mu  sync.Mutex
+	scs map[balancer.SubConn]struct{}
+}
+
+func newTriedContext(ctx context.Context) context.Context {
+	if triedFromContext(ctx) != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, triedKey{}, &triedNodes{scs: make(map[balancer.SubConn]struct{})})
+}
+
+func triedFromContext(ctx context.Context) *triedNodes {
+	t, _ := ctx.Value(triedKey{}).(*triedNodes)
+	return t
+}
+
+type triedPickerBuilder struct{}
+
+func (*triedPickerBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+	scs := make([]balancer.SubConn, 0, len(info.ReadySCs))
+	for sc := range info.ReadySCs {
+		scs = append(scs, sc)
+	}
+	return &triedPicker{
+		subConns: scs,
+		next:     uint32(rand.Intn(len(scs))),
+	}
+}
+
+type triedPicker struct {
+	subConns []balancer.SubConn
+	next     uint32
+}
+
+// Pick 轮询选择节点, 跳过请求已使用的节点, 全部使用过时按轮询选择
+func (p *triedPicker) Pick(info balancer.PickInfo) (balancer.PickResult, error) {
+	n := uint32(len(p.subConns))
+	start := atomic.AddUint32(&p.next, 1)
+	tried := triedFromContext(info.Ctx)
+	if tried == nil {
+		return balancer.PickResult{SubConn: p.subConns[start%n]}, nil
+	}
+	tried.mu.Lock()
+	defer tried.mu.Unlock()
+	sc := p.subConns[start%n]
+	for i := uint32(0); i < n; i++ {
+		if _, has := tried.scs[p.subConns[(start+i)%n]]; !has {
+			sc = p.subConns[(start+i)%n]
+			break
+		}
+	}
+	tried.scs[sc] = struct{}{}
+	return balancer.PickResult{SubConn: sc}, nil
+}
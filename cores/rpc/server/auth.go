@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+
+	"github.com/wangshanqi84-gif/sagittarius/cores/auth"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// authenticate req为nil时表示stream, 签名类认证无法校验body
+func authenticate(ctx context.Context, a auth.Authenticator, fullMethod string, req interface{}) (context.Context, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		md = metadata.New(nil)
+	}
+	ar := &auth.Request{
+		Method: "POST",
+		Path:   fullMethod,
+		Header: func(key string) string {
+			if vs := md.Get(key); len(vs) > 0 {
+				return vs[0]
+			}
+			return ""
+		},
+		Streaming: req == nil,
+	}
+	if m, ok := req.(proto.Message); ok {
+		body, err := (proto.MarshalOptions{Deterministic: true}).Marshal(m)
+		if err != nil {
+			return ctx, status.Error(codes.InvalidArgument, err.Error())
+		}
+		ar.Body = body
+	}
+	p, err := a.Authenticate(ctx, ar)
+	if err != nil {
+		return ctx, status.Error(codes.Unauthenticated, "unauthenticated")
+	}
+	return auth.NewContext(ctx, p), nil
+}
+
+func skipMethod(fullMethod string, skips []string) bool {
+	for _, m := range skips {
+		if m == fullMethod {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthServerUnaryInterceptor 认证请求, skipMethods中的方法(如健康检查)不认证
+func AuthServerUnaryInterceptor(a auth.Authenticator, skipMethods ...string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		if skipMethod(info.FullMethod, skipMethods) {
+			return handler(ctx, req)
+		}
+		if ctx, err = authenticate(ctx, a, info.FullMethod, req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func AuthServerStreamInterceptor(a auth.Authenticator, skipMethods ...string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if skipMethod(info.FullMethod, skipMethods) {
+			return handler(srv, ss)
+		}
+		ctx, err := authenticate(ss.Context(), a, info.FullMethod, nil)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &ctxServerStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func checkScopes(ctx context.Context, scopes []string) error {
+	if len(scopes) == 0 {
+		return nil
+	}
+	p, ok := auth.FromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "unauthenticated")
+	}
+	if !p.HasScopes(scopes...) {
+		return status.Error(codes.PermissionDenied, "permission denied")
+	}
+	return nil
+}
+
+// ScopeServerUnaryInterceptor 按FullMethod校验scope, 未配置的方法不校验
+func ScopeServerUnaryInterceptor(scopes map[string][]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		if err = checkScopes(ctx, scopes[info.FullMethod]); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func ScopeServerStreamInterceptor(scopes map[string][]string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := checkScopes(ss.Context(), scopes[info.FullMethod]); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/wangshanqi84-gif/sagittarius/cores/auth"
+	gCtx "github.com/wangshanqi84-gif/sagittarius/cores/context"
+	"github.com/wangshanqi84-gif/sagittarius/cores/idempotency"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+const (
+	idempotentReplayedKey = "idempotent-replayed"
+)
+
+// cacheableCode 确定性的错误结果可以保存, 其他错误释放key允许重试
+func cacheableCode(code codes.Code) bool {
+	switch code {
+	case codes.OK, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists,
+		codes.PermissionDenied, codes.FailedPrecondition, codes.OutOfRange,
+		codes.Unimplemented, codes.Unauthenticated:
+		return true
+	}
+	return false
+}
+
+// replyType 根据FullMethod从全局注册表查找应答类型
+func replyType(fullMethod string) (proto.Message, error) {
+	name := strings.TrimPrefix(fullMethod, "/")
+	idx := strings.LastIndex(name, "/")
+	if idx < 0 {
+		return nil, errors.New("invalid full method")
+	}
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name[:idx]))
+	if err != nil {
+		return nil, err
+	}
+	svc, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, errors.New("not a service")
+	}
+	md := svc.Methods().ByName(protoreflect.Name(name[idx+1:]))
+	if md == nil {
+		return nil, errors.New("method not found")
+	}
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(md.Output().FullName())
+	if err != nil {
+		return nil, err
+	}
+	return mt.New().Interface(), nil
+}
+
+func replayReply(ctx context.Context, fullMethod string, rec *idempotency.Record) (interface{}, error) {
+	_ = grpc.SetHeader(ctx, metadata.Pairs(idempotentReplayedKey, "true"))
+	if code := codes.Code(rec.Status); code != codes.OK {
+		return nil, status.Error(code, rec.Message)
+	}
+	reply, err := replyType(fullMethod)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "idempotency replay: "+err.Error())
+	}
+	if err = proto.Unmarshal(rec.Body, reply); err != nil {
+		return nil, status.Error(codes.Internal, "idempotency replay: "+err.Error())
+	}
+	return reply, nil
+}
+
+// IdempotencyServerUnaryInterceptor 按idempotency-key metadata保证请求只执行一次
+// 相同key重放已保存的结果, 处理中的重复请求返回Aborted, key对应不同请求返回InvalidArgument
+// 临时性错误和panic不保存, 客户端可使用相同key重试; redis出错时返回Unavailable
+// 需放在AuthServerUnaryInterceptor之后使key按principal隔离
+func IdempotencyServerUnaryInterceptor(g *idempotency.Guard) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		rpcMD, ok := metadata.FromIncomingContext(ctx)
+		if !ok {
+			rpcMD = metadata.New(nil)
+		}
+		key := gCtx.GetUberIdempotencyKey(gCtx.Metadata{MD: rpcMD})
+		if key == "" {
+			if g.Required() {
+				return nil, status.Error(codes.InvalidArgument, idempotency.ErrKeyRequired.Error())
+			}
+			return handler(ctx, req)
+		}
+		if !idempotency.ValidKey(key) {
+			return nil, status.Error(codes.InvalidArgument, idempotency.ErrInvalidKey.Error())
+		}
+		scoped := key
+		if p, ok := auth.FromContext(ctx); ok {
+			scoped = p.Subject + ":" + key
+		}
+		var body []byte
+		if m, ok := req.(proto.Message); ok {
+			if body, err = (proto.MarshalOptions{Deterministic: true}).Marshal(m); err != nil {
+				return nil, status.Error(codes.InvalidArgument, err.Error())
+			}
+		}
+		entry, rec, err := g.Begin(ctx, scoped, idempotency.Fingerprint([]byte(info.FullMethod), body))
+		switch {
+		case errors.Is(err, idempotency.ErrInProgress):
+			return nil, status.Error(codes.Aborted, err.Error())
+		case errors.Is(err, idempotency.ErrMismatch):
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		case err != nil:
+			return nil, status.Error(codes.Unavailable, "idempotency store unavailable")
+		}
+		if rec != nil {
+			return replayReply(ctx, info.FullMethod, rec)
+		}
+
+		// 客户端超时后ctx已取消, 保存和释放使用不随请求取消的ctx, 避免key一直处于处理中
+		storeCtx := context.WithoutCancel(ctx)
+		completed := false
+		defer func() {
+			// panic时释放key
+			if !completed {
+				_ = entry.Release(storeCtx)
+			}
+		}()
+		resp, err = handler(ctx, req)
+		completed = true
+		if res, ok := newRecord(resp, err); ok {
+			_ = entry.Complete(storeCtx, res)
+		} else {
+			_ = entry.Release(storeCtx)
+		}
+		return resp, err
+	}
+}
+
+// newRecord 构建需要保存的结果, 不可保存时返回false
+func newRecord(resp interface{}, err error) (*idempotency.Record, bool) {
+	st := status.Convert(err)
+	if !cacheableCode(st.Code()) {
+		return nil, false
+	}
+	res := &idempotency.Record{
+		Status:  int(st.Code()),
+		Message: st.Message(),
+	}
+	if err != nil {
+		return res, true
+	}
+	m, ok := resp.(proto.Message)
+	if !ok {
+		return nil, false
+	}
+	body, err := proto.Marshal(m)
+	if err != nil {
+		return nil, false
+	}
+	res.Body = body
+	return res, true
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/wangshanqi84-gif/sagittarius/cores/auth"
 	gCtx "github.com/wangshanqi84-gif/sagittarius/cores/context"
 	"github.com/wangshanqi84-gif/sagittarius/cores/logger"
 
@@ -46,6 +47,43 @@ func RecoverServerInterceptor(lgr *logger.Logger) grpc.UnaryServerInterceptor {
 	}
 }
 
+func requestIDContext(ctx context.Context) context.Context {
+	rpcMD, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		rpcMD = metadata.New(nil)
+	}
+	id := gCtx.GetUberRequestID(gCtx.Metadata{MD: rpcMD})
+	if !gCtx.ValidRequestID(id) {
+		id = gCtx.NewRequestID()
+	}
+	// 应答header返回请求ID
+	_ = grpc.SetHeader(ctx, metadata.Pairs(gCtx.UberRequestIDKey(), id))
+	return gCtx.NewRequestIDContext(ctx, id)
+}
+
+// RequestIDServerUnaryInterceptor 沿用上游传入的请求ID或生成新ID
+func RequestIDServerUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		return handler(requestIDContext(ctx), req)
+	}
+}
+
+// ctxServerStream 替换stream的context
+type ctxServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *ctxServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func RequestIDServerStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &ctxServerStream{ServerStream: ss, ctx: requestIDContext(ss.Context())})
+	}
+}
+
 func TracingServerUnaryInterceptor(tracer opentracing.Tracer) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
 		rpcMD, ok := metadata.FromIncomingContext(ctx)
@@ -109,6 +147,9 @@ func AccessServerUnaryInterceptor(lgr *logger.Logger, requestEnable bool) grpc.U
 				"Method": info.FullMethod,
 				"Cost":   fmt.Sprintf("%dms", time.Now().UnixMilli()-start),
 			}
+			if p, ok := auth.FromContext(ctx); ok {
+				logData["Principal"] = p
+			}
 			if requestEnable {
 				logData["Request"] = req
 			}
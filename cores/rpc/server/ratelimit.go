@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"net"
+
+	gCtx "github.com/wangshanqi84-gif/sagittarius/cores/context"
+	"github.com/wangshanqi84-gif/sagittarius/cores/ratelimit"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// RateLimitKeyFunc 提取限流key, 返回空字符串时不限流
+type RateLimitKeyFunc func(ctx context.Context, fullMethod string) string
+
+// LimitByIP 按对端ip限流
+func LimitByIP(ctx context.Context, _ string) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		host = p.Addr.String()
+	}
+	return "ip:" + host
+}
+
+// LimitByMethod 按grpc方法限流
+func LimitByMethod(_ context.Context, fullMethod string) string {
+	return "route:" + fullMethod
+}
+
+// LimitByService 按上游服务限流, 从incoming metadata读取上游服务信息, 不依赖拦截器顺序
+func LimitByService(ctx context.Context, _ string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	sk := gCtx.GetUberMeta(gCtx.Metadata{MD: md})
+	if sk == "" {
+		return ""
+	}
+	return "service:" + sk
+}
+
+// LimitGlobal 所有请求共用一个限额
+func LimitGlobal(_ context.Context, _ string) string {
+	return "global"
+}
+
+// LimitKeyFunc 根据配置名称返回key提取方法, 未知名称返回nil
+func LimitKeyFunc(name string) RateLimitKeyFunc {
+	switch name {
+	case ratelimit.KeyIP:
+		return LimitByIP
+	case ratelimit.KeyRoute:
+		return LimitByMethod
+	case ratelimit.KeyService:
+		return LimitByService
+	case ratelimit.KeyGlobal:
+		return LimitGlobal
+	}
+	return nil
+}
+
+func limitAllow(ctx context.Context, limiter ratelimit.Limiter, keyFunc RateLimitKeyFunc, fullMethod string) error {
+	key := keyFunc(ctx, fullMethod)
+	if key == "" {
+		return nil
+	}
+	res, err := limiter.Allow(ctx, key)
+	if err != nil || res.Allowed {
+		// limiter出错时放行
+		return nil
+	}
+	return status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry after %v", res.RetryAfter)
+}
+
+// RateLimitServerUnaryInterceptor 超过限额时返回ResourceExhausted
+func RateLimitServerUnaryInterceptor(limiter ratelimit.Limiter, keyFunc RateLimitKeyFunc) grpc.UnaryServerInterceptor {
+	if keyFunc == nil {
+		keyFunc = LimitByIP
+	}
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		if err = limitAllow(ctx, limiter, keyFunc, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// RateLimitServerStreamInterceptor 建立stream时判定一次
+func RateLimitServerStreamInterceptor(limiter ratelimit.Limiter, keyFunc RateLimitKeyFunc) grpc.StreamServerInterceptor {
+	if keyFunc == nil {
+		keyFunc = LimitByIP
+	}
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := limitAllow(ss.Context(), limiter, keyFunc, info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
@@ -4,6 +4,10 @@ import (
 	"context"
 	"crypto/tls"
 	"net"
+	"sort"
+	"sync/atomic"
+
+	gServer "github.com/wangshanqi84-gif/sagittarius/cores/server"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
@@ -32,15 +36,17 @@ func TLS(tlsCfg *tls.Config) Option {
 	}
 }
 
+// UnaryInterceptor 追加拦截器, 按Option顺序执行, 先追加的在外层
 func UnaryInterceptor(in ...grpc.UnaryServerInterceptor) Option {
 	return func(s *Server) {
-		s.unaryInts = in
+		s.unaryInts = append(s.unaryInts, in...)
 	}
 }
 
+// StreamInterceptor 追加拦截器, 按Option顺序执行, 先追加的在外层
 func StreamInterceptor(in ...grpc.StreamServerInterceptor) Option {
 	return func(s *Server) {
-		s.streamInts = in
+		s.streamInts = append(s.streamInts, in...)
 	}
 }
 
@@ -66,6 +72,8 @@ type Server struct {
 	unaryInts  []grpc.UnaryServerInterceptor
 	streamInts []grpc.StreamServerInterceptor
 	onStop     func()
+	tracker    *gServer.Tracker
+	notServing atomic.Bool
 }
 
 func NewServer(opts ...Option) *Server {
@@ -73,13 +81,15 @@ func NewServer(opts ...Option) *Server {
 		network: "tcp",
 		address: ":9901",
 		health:  health.NewServer(),
+		tracker: gServer.NewTracker(),
 	}
 	for _, o := range opts {
 		o(srv)
 	}
+	// 最外层记录处理中的请求
 	grpcOpts := []grpc.ServerOption{
-		grpc.ChainUnaryInterceptor(srv.unaryInts...),
-		grpc.ChainStreamInterceptor(srv.streamInts...),
+		grpc.ChainUnaryInterceptor(append([]grpc.UnaryServerInterceptor{srv.trackUnary}, srv.unaryInts...)...),
+		grpc.ChainStreamInterceptor(append([]grpc.StreamServerInterceptor{srv.trackStream}, srv.streamInts...)...),
 	}
 	if srv.tlsCfg != nil {
 		grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(srv.tlsCfg)))
@@ -101,16 +111,89 @@ func (s *Server) Start(ctx context.Context) error {
 		return err
 	}
 	s.health.Resume()
+	if s.notServing.Load() {
+		s.SetServing(false)
+	}
 	return s.Serve(sock)
 }
 
-func (s *Server) Stop(_ context.Context) error {
+// SetServing 设置健康检查状态, 用于同步依赖的检查结果, Drain后不再生效
+func (s *Server) SetServing(serving bool) {
+	s.notServing.Store(!serving)
+	status := grpc_health_v1.HealthCheckResponse_SERVING
+	if !serving {
+		status = grpc_health_v1.HealthCheckResponse_NOT_SERVING
+	}
+	s.health.SetServingStatus("", status)
+}
+
+// Drain 健康检查切换为NOT_SERVING, 请求继续处理
+func (s *Server) Drain() {
+	s.tracker.Drain()
+	s.health.Shutdown()
+}
+
+func (s *Server) Ready() bool {
+	return s.tracker.Ready()
+}
+
+// InFlight 处理中的请求
+func (s *Server) InFlight() []gServer.Call {
+	return s.tracker.InFlight()
+}
+
+// Stop 排空后优雅关闭, ctx结束时强制关闭, 返回的错误包含未完成的请求
+func (s *Server) Stop(ctx context.Context) error {
 	if s.onStop != nil {
 		s.onStop()
 	}
-	// 健康检查关闭
-	s.health.Shutdown()
+	s.Drain()
 	// 优雅关闭
-	s.GracefulStop()
-	return nil
+	done := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		err := s.tracker.TimeoutError()
+		s.Server.Stop()
+		<-done
+		if err != nil {
+			return err
+		}
+		return ctx.Err()
+	}
+}
+
+// RouteList 实现server.RouteLister, 按方法名排序
+func (s *Server) RouteList() []gServer.Route {
+	var routes []gServer.Route
+	for name, info := range s.GetServiceInfo() {
+		for _, m := range info.Methods {
+			method := "unary"
+			if m.IsClientStream || m.IsServerStream {
+				method = "stream"
+			}
+			routes = append(routes, gServer.Route{Method: method, Path: "/" + name + "/" + m.Name})
+		}
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		return routes[i].Path < routes[j].Path
+	})
+	return routes
+}
+
+func (s *Server) trackUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	done := s.tracker.Begin(info.FullMethod)
+	defer done()
+	return handler(ctx, req)
+}
+
+func (s *Server) trackStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	done := s.tracker.Begin(info.FullMethod)
+	defer done()
+	return handler(srv, ss)
 }
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	drainPollInterval = 50 * time.Millisecond
+	// drainReportLimit 超时错误中最多列出的请求数
+	drainReportLimit = 20
+)
+
+var (
+	ErrDrainTimeout = errors.New("drain timeout")
+)
+
+// Drainer 支持排空的服务, 关闭前先调用Drain
+// Drain后服务标记为未就绪, 已有和新到的请求仍正常处理, 直到Stop
+type Drainer interface {
+	Drain()
+	Ready() bool
+}
+
+// Call 处理中的请求
+type Call struct {
+	Name  string
+	Start time.Time
+}
+
+// Tracker 记录就绪状态和处理中的请求, 供各协议server排空使用
+type Tracker struct {
+	draining atomic.Bool
+
+	mu    sync.Mutex
+	seq   uint64
+	calls map[uint64]Call
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{
+		calls: make(map[uint64]Call),
+	}
+}
+
+// Drain 标记为排空中, 可重复调用
+func (t *Tracker) Drain() {
+	t.draining.Store(true)
+}
+
+func (t *Tracker) Ready() bool {
+	return !t.draining.Load()
+}
+
+// Begin 开始处理请求, 返回结束回调
+func (t *Tracker) Begin(name string) func() {
+	t.mu.Lock()
+	t.seq++
+	id := t.seq
+	t.calls[id] = Call{Name: name, Start: time.Now()}
+	t.mu.Unlock()
+	return func() {
+		t.mu.Lock()
+		delete(t.calls, id)
+		t.mu.Unlock()
+	}
+}
+
+func (t *Tracker) Count() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.calls)
+}
+
+// InFlight 处理中的请求, 按开始时间排序
+func (t *Tracker) InFlight() []Call {
+	t.mu.Lock()
+	calls := make([]Call, 0, len(t.calls))
+	for _, c := range t.calls {
+		calls = append(calls, c)
+	}
+	t.mu.Unlock()
+	sort.Slice(calls, func(i, j int) bool {
+		return calls[i].Start.Before(calls[j].Start)
+	})
+	return calls
+}
+
+// Wait 等待处理中的请求全部结束, ctx结束时返回包含未完成请求的ErrDrainTimeout
+func (t *Tracker) Wait(ctx context.Context) error {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for {
+		if t.Count() == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return t.TimeoutError()
+		case <-ticker.C:
+		}
+	}
+}
+
+// TimeoutError 列出未完成请求的超时错误, 没有未完成请求时返回nil
+func (t *Tracker) TimeoutError() error {
+	calls := t.InFlight()
+	if len(calls) == 0 {
+		return nil
+	}
+	now := time.Now()
+	items := make([]string, 0, drainReportLimit)
+	for idx, c := range calls {
+		if idx == drainReportLimit {
+			items = append(items, "...")
+			break
+		}
+		items = append(items, fmt.Sprintf("%s(%s)", c.Name, now.Sub(c.Start).Truncate(time.Millisecond)))
+	}
+	return fmt.Errorf("%w, %d in-flight: %s", ErrDrainTimeout, len(calls), strings.Join(items, ", "))
+}
@@ -0,0 +1,32 @@
+package server
+
+import (
+	"reflect"
+	"runtime"
+)
+
+// Route 已注册的路由, 用于管理端口展示
+// http: Method为http method; rpc: Method为unary/stream; websocket: Path为消息id; socketio: Path为namespace/event
+type Route struct {
+	Method  string `json:"method,omitempty"`
+	Path    string `json:"path"`
+	Handler string `json:"handler,omitempty"`
+}
+
+// RouteLister 可列出路由的服务
+type RouteLister interface {
+	RouteList() []Route
+}
+
+// FuncName 函数名, 用于展示路由的处理函数
+func FuncName(fn interface{}) string {
+	v := reflect.ValueOf(fn)
+	if !v.IsValid() || v.Kind() != reflect.Func || v.IsNil() {
+		return ""
+	}
+	f := runtime.FuncForPC(v.Pointer())
+	if f == nil {
+		return ""
+	}
+	return f.Name()
+}
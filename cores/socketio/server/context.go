@@ -21,6 +21,8 @@ type Context struct {
 	data  string
 	event string
 	resp  string
+	// status JsonErr写入的业务错误码, 用于指标统计
+	status int
 }
 
 func newContext() *Context {
@@ -43,6 +45,7 @@ func (c *Context) reset() *Context {
 	c.data = ""
 	c.event = ""
 	c.resp = ""
+	c.status = 0
 	c.ctx = context.TODO()
 	return c
 }
@@ -94,6 +97,7 @@ func (c *Context) JsonOK(data interface{}) error {
 		return err
 	}
 	c.resp = string(bs)
+	c.status = 0
 	return nil
 }
 
@@ -108,6 +112,7 @@ func (c *Context) JsonErr(err error) error {
 		return err
 	}
 	c.resp = string(bs)
+	c.status = ge.Code()
 	return nil
 }
 
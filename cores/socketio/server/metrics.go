@@ -0,0 +1,67 @@
+package server
+
+import (
+	"strconv"
+	"time"
+
+	gCtx "github.com/wangshanqi84-gif/sagittarius/cores/context"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	ioEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "socketio_server_events_total",
+		Help: "Total number of socket.io events handled by the server.",
+	}, []string{"upstream", "namespace", "event", "status"})
+	ioDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "socketio_server_event_duration_seconds",
+		Help:    "Histogram of socket.io event handling latency (seconds).",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"upstream", "namespace", "event"})
+	ioInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "socketio_server_events_in_flight",
+		Help: "Number of socket.io events currently being handled.",
+	}, []string{"upstream", "namespace", "event"})
+	ioErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "socketio_server_event_errors_total",
+		Help: "Total number of socket.io events answered with an error code or panicked.",
+	}, []string{"upstream", "namespace", "event", "code"})
+	ioConnections = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "socketio_server_connections",
+		Help: "Number of open socket.io connections.",
+	}, []string{"namespace"})
+)
+
+func init() {
+	prometheus.MustRegister(ioEvents, ioDuration, ioInFlight, ioErrors, ioConnections)
+}
+
+// MetricsHandler prometheus RED指标, 按上游服务、namespace及event统计, 上游服务取自握手请求, 非服务调用为空
+// 放在PanicHandler之后, JsonErr的错误码记为error, panic记为panic
+func MetricsHandler() core {
+	return func(c *Context) {
+		ns, event := c.conn.Namespace(), c.event
+		upstream := gCtx.GetUberHttpHeader(c.conn.RemoteHeader())
+		start := time.Now()
+		inFlight := ioInFlight.WithLabelValues(upstream, ns, event)
+		inFlight.Inc()
+		finished := false
+		defer func() {
+			inFlight.Dec()
+			status := "ok"
+			switch {
+			case !finished:
+				status = "panic"
+				ioErrors.WithLabelValues(upstream, ns, event, status).Inc()
+			case c.status != 0:
+				status = "error"
+				ioErrors.WithLabelValues(upstream, ns, event, strconv.Itoa(c.status)).Inc()
+			}
+			ioEvents.WithLabelValues(upstream, ns, event, status).Inc()
+			ioDuration.WithLabelValues(upstream, ns, event).Observe(time.Since(start).Seconds())
+		}()
+		c.Next()
+		finished = true
+	}
+}
@@ -33,6 +33,14 @@ func PanicHandler(lgr *logger.Logger) core {
 	}
 }
 
+// RequestIDHandler 每条消息生成新的请求ID写入Ctx(), 消息本身不携带header
+func RequestIDHandler() core {
+	return func(c *Context) {
+		c.ctx = gCtx.NewRequestIDContext(c.ctx, gCtx.NewRequestID())
+		c.Next()
+	}
+}
+
 func TracingHandler(tracer opentracing.Tracer) core {
 	return func(c *Context) {
 
@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
+	gServer "github.com/wangshanqi84-gif/sagittarius/cores/server"
+
 	skio "github.com/googollee/go-socket.io"
 	"github.com/googollee/go-socket.io/engineio"
 	"github.com/googollee/go-socket.io/engineio/transport"
@@ -65,8 +68,9 @@ type Engine struct {
 	pingTimeout      time.Duration
 	pingInterval     time.Duration
 
-	pool  sync.Pool
-	cores []core
+	pool   sync.Pool
+	cores  []core
+	routes []gServer.Route
 }
 
 func NewServer(opts ...Option) *Engine {
@@ -94,6 +98,7 @@ func (s *Engine) Use(cores ...core) {
 }
 
 func (s *Engine) OnEvent(namespace string, event string, f core) {
+	s.addRoute(namespace, event, f)
 	s.sioSrv.OnEvent(namespace, event, func(conn skio.Conn, data string) string {
 		cCtx, ok := conn.Context().(*Context)
 		if ok {
@@ -119,6 +124,7 @@ func (s *Engine) OnEventForAllNamespace(event string, f core) {
 		if ns[0] != '/' {
 			ns = fmt.Sprintf("/%s", ns)
 		}
+		s.addRoute(ns, event, f)
 		s.sioSrv.OnEvent(ns, event, func(conn skio.Conn, data string) string {
 			cCtx, ok := conn.Context().(*Context)
 			if ok {
@@ -137,6 +143,15 @@ func (s *Engine) OnEventForAllNamespace(event string, f core) {
 	}
 }
 
+func (s *Engine) addRoute(namespace string, event string, f core) {
+	s.routes = append(s.routes, gServer.Route{Path: strings.TrimRight(namespace, "/") + "/" + event, Handler: gServer.FuncName(f)})
+}
+
+// RouteList 实现server.RouteLister, Path为namespace/event
+func (s *Engine) RouteList() []gServer.Route {
+	return append([]gServer.Route(nil), s.routes...)
+}
+
 func (s *Engine) Start(ctx context.Context) error {
 	if s.port == "" {
 		panic("must set listen port.")
@@ -211,12 +226,14 @@ func (s *Engine) newSocketIO() {
 			cCtx := s.pool.Get().(*Context)
 			cCtx.conn = conn
 			conn.SetContext(cCtx)
+			ioConnections.WithLabelValues(ns).Inc()
 			log.Println("connect, id:", conn.URL(), conn.ID())
 			return nil
 		})
 		s.sioSrv.OnDisconnect(ns, func(conn skio.Conn, msg string) {
 			cCtx, ok := conn.Context().(*Context)
 			if ok {
+				ioConnections.WithLabelValues(ns).Dec()
 				if s.connCloseHandler != nil {
 					s.connCloseHandler(cCtx)
 				}
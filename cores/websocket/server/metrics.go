@@ -0,0 +1,69 @@
+package server
+
+import (
+	"strconv"
+	"time"
+
+	gCtx "github.com/wangshanqi84-gif/sagittarius/cores/context"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	wsMessages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "websocket_server_messages_total",
+		Help: "Total number of WebSocket messages handled by the server.",
+	}, []string{"upstream", "msg_id", "status"})
+	wsDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "websocket_server_message_duration_seconds",
+		Help:    "Histogram of WebSocket message handling latency (seconds).",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"upstream", "msg_id"})
+	wsInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "websocket_server_messages_in_flight",
+		Help: "Number of WebSocket messages currently being handled.",
+	}, []string{"upstream", "msg_id"})
+	wsErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "websocket_server_message_errors_total",
+		Help: "Total number of WebSocket messages whose handler panicked.",
+	}, []string{"upstream", "msg_id"})
+	wsConnections = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "websocket_server_connections",
+		Help: "Number of open WebSocket connections.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(wsMessages, wsDuration, wsInFlight, wsErrors, wsConnections)
+}
+
+// MetricsHandler prometheus RED指标, 按上游服务及消息id统计, 上游服务取自握手请求, 非服务调用为空
+// 放在PanicHandler之后, panic的消息记为错误
+func MetricsHandler() core {
+	return func(c *Context) {
+		id := "unknown"
+		if h, ok := c.Header().(IHeader); ok {
+			id = strconv.Itoa(int(h.MsgID()))
+		}
+		upstream := ""
+		if c.Conn() != nil {
+			upstream = gCtx.GetUberHttpHeader(c.Conn().Header())
+		}
+		start := time.Now()
+		inFlight := wsInFlight.WithLabelValues(upstream, id)
+		inFlight.Inc()
+		finished := false
+		defer func() {
+			inFlight.Dec()
+			status := "ok"
+			if !finished {
+				status = "panic"
+				wsErrors.WithLabelValues(upstream, id).Inc()
+			}
+			wsMessages.WithLabelValues(upstream, id, status).Inc()
+			wsDuration.WithLabelValues(upstream, id).Observe(time.Since(start).Seconds())
+		}()
+		c.Next()
+		finished = true
+	}
+}
@@ -38,6 +38,22 @@ func PanicHandler(lgr *logger.Logger) core {
 	}
 }
 
+// RequestIDHandler 消息本身不携带header, 使用握手请求的x-request-id作为请求ID
+// 握手未携带时每条消息生成新的请求ID写入Ctx()
+func RequestIDHandler() core {
+	return func(c *Context) {
+		id := ""
+		if c.conn != nil {
+			id = c.conn.RequestID()
+		}
+		if id == "" {
+			id = gCtx.NewRequestID()
+		}
+		c.ctx = gCtx.NewRequestIDContext(c.ctx, id)
+		c.Next()
+	}
+}
+
 func TracingHandler(tracer opentracing.Tracer) core {
 	return func(c *Context) {
 		buffer := bytes.NewBuffer(c.Header().(IHeader).Trace())
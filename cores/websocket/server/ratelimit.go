@@ -0,0 +1,76 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	gCtx "github.com/wangshanqi84-gif/sagittarius/cores/context"
+	"github.com/wangshanqi84-gif/sagittarius/cores/ratelimit"
+)
+
+// RateLimitKeyFunc 提取限流key, 返回空字符串时不限流
+type RateLimitKeyFunc func(c *Context) string
+
+// LimitByIP 按连接ip限流
+func LimitByIP(c *Context) string {
+	host, _, err := net.SplitHostPort(c.Conn().RemoteAddr())
+	if err != nil {
+		return "ip:" + c.Conn().RemoteAddr()
+	}
+	return "ip:" + host
+}
+
+// LimitByMessage 按消息ID限流
+func LimitByMessage(c *Context) string {
+	return fmt.Sprintf("route:%d", c.Header().(IHeader).MsgID())
+}
+
+// LimitByService 按握手header中的上游服务限流
+func LimitByService(c *Context) string {
+	sk := gCtx.GetUberHttpHeader(c.Conn().Header())
+	if sk == "" {
+		return ""
+	}
+	return "service:" + strings.TrimSpace(sk)
+}
+
+// LimitGlobal 所有消息共用一个限额
+func LimitGlobal(_ *Context) string {
+	return "global"
+}
+
+// LimitKeyFunc 根据配置名称返回key提取方法, 未知名称返回nil
+func LimitKeyFunc(name string) RateLimitKeyFunc {
+	switch name {
+	case ratelimit.KeyIP:
+		return LimitByIP
+	case ratelimit.KeyRoute:
+		return LimitByMessage
+	case ratelimit.KeyService:
+		return LimitByService
+	case ratelimit.KeyGlobal:
+		return LimitGlobal
+	}
+	return nil
+}
+
+// RateLimitHandler 超过限额时丢弃消息, limiter出错时放行
+func RateLimitHandler(limiter ratelimit.Limiter, keyFunc RateLimitKeyFunc) core {
+	if keyFunc == nil {
+		keyFunc = LimitByIP
+	}
+	return func(c *Context) {
+		key := keyFunc(c)
+		if key == "" {
+			c.Next()
+			return
+		}
+		res, err := limiter.Allow(c.Ctx(), key)
+		if err == nil && !res.Allowed {
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
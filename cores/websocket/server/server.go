@@ -6,10 +6,14 @@ import (
 	"log"
 	"net/http"
 	"runtime/debug"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
 
+	gCtx "github.com/wangshanqi84-gif/sagittarius/cores/context"
+	gServer "github.com/wangshanqi84-gif/sagittarius/cores/server"
+
 	"github.com/gorilla/websocket"
 )
 
@@ -22,6 +26,7 @@ type Conn struct {
 	remoteAddr string
 	header     http.Header
 	params     map[string]string
+	requestID  string
 }
 
 func (c *Conn) Close() error {
@@ -55,21 +60,38 @@ func (c *Conn) serve() {
 				log.Println(fmt.Sprintf("websocket read message error:%v", err))
 				return
 			}
-			cores := c.server.findCore(ctx.header.(IHeader).MsgID())
-			if cores == nil || len(cores) == 0 {
-				cores = c.server.defaultCore()
-			}
-			ctx.cores = cores
-			ctx.do()
-			c.server.pool.Put(ctx.reset())
+			c.handle(ctx)
 		}
 	}
 }
 
+func (c *Conn) handle(ctx *Context) {
+	msgID := ctx.header.(IHeader).MsgID()
+	done := c.server.tracker.Begin(fmt.Sprintf("%s msg:%d", c.remoteAddr, msgID))
+	defer done()
+
+	cores := c.server.findCore(msgID)
+	if cores == nil || len(cores) == 0 {
+		cores = c.server.defaultCore()
+	}
+	ctx.cores = cores
+	ctx.do()
+	c.server.pool.Put(ctx.reset())
+}
+
 func (c *Conn) Header() http.Header {
 	return c.header
 }
 
+// RequestID 握手请求携带的x-request-id, 未携带或不合法时为空
+func (c *Conn) RequestID() string {
+	return c.requestID
+}
+
+func (c *Conn) RemoteAddr() string {
+	return c.remoteAddr
+}
+
 func (c *Conn) Param(key string) string {
 	return c.params[key]
 }
@@ -97,6 +119,7 @@ type Engine struct {
 
 	mu               sync.Mutex
 	activeConn       map[*Conn]struct{}
+	stopped          bool
 	handlers         map[int32][]core
 	defaults         []core
 	pool             sync.Pool
@@ -108,11 +131,13 @@ type Engine struct {
 	onConnect        []func(*Conn) error
 	onDisconnect     []func(*Conn)
 	bodyReader       func(c *Context, v interface{}) error
+	tracker          *gServer.Tracker
 }
 
 func NewServer(opts ...Option) *Engine {
 	engine := &Engine{
-		mux: newMux(),
+		mux:     newMux(),
+		tracker: gServer.NewTracker(),
 	}
 	group := &Group{
 		svr: engine,
@@ -155,6 +180,15 @@ func (s *Engine) Start(ctx context.Context) error {
 		if !websocket.IsWebSocketUpgrade(r) {
 			return
 		}
+		// 排空中仍接受新连接, 只在就绪检查中报告未就绪, Stop后拒绝
+		if s.isStopped() {
+			http.Error(w, "server is stopping", http.StatusServiceUnavailable)
+			return
+		}
+		requestID := gCtx.GetUberHttpRequestIDHeader(r.Header)
+		if !gCtx.ValidRequestID(requestID) {
+			requestID = ""
+		}
 		c, err := s.mux.upGrader.Upgrade(w, r, nil)
 		if err != nil {
 			return
@@ -162,6 +196,9 @@ func (s *Engine) Start(ctx context.Context) error {
 
 		cCtx := context.WithValue(ctx, "upgrade", time.Now().Format("2006-01-02 15:04:05.000"))
 		cCtx = context.WithValue(cCtx, "remote", c.RemoteAddr().String())
+		if requestID != "" {
+			cCtx = gCtx.NewRequestIDContext(cCtx, requestID)
+		}
 
 		nCtx, fn := context.WithCancel(cCtx)
 		cn := Conn{
@@ -173,13 +210,20 @@ func (s *Engine) Start(ctx context.Context) error {
 			header:     r.Header.Clone(),
 			params:     make(map[string]string),
 			remoteAddr: c.RemoteAddr().String(),
+			requestID:  requestID,
 		}
 		q := r.URL.Query()
 		for k, vs := range q {
 			cn.params[k] = vs[0]
 		}
+		// 升级期间Stop已关闭全部连接时直接关闭
+		if !s.trackConn(&cn, true) {
+			msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutdown")
+			_ = c.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+			_ = c.Close()
+			return
+		}
 		go func(c *Conn) {
-			s.trackConn(c, true)
 			defer s.trackConn(c, false)
 
 			cn.serve()
@@ -217,26 +261,103 @@ func (s *Engine) Start(ctx context.Context) error {
 	return nil
 }
 
-func (s *Engine) Stop(_ context.Context) error {
+// Drain 标记为未就绪, 新连接和已建立的连接继续处理, Stop后才拒绝新连接
+func (s *Engine) Drain() {
+	s.tracker.Drain()
+}
+
+func (s *Engine) Ready() bool {
+	return s.tracker.Ready()
+}
+
+// InFlight 处理中的消息
+func (s *Engine) InFlight() []gServer.Call {
+	return s.tracker.InFlight()
+}
+
+// Stop 停止监听, 等待处理中的消息结束后以1001关闭所有连接
+// ctx结束时直接关闭, 返回的错误包含未完成的消息
+func (s *Engine) Stop(ctx context.Context) error {
+	s.Drain()
 	if len(s.onStop) > 0 {
 		for _, f := range s.onStop {
 			f()
 		}
 	}
-	return s.httpServer.Close()
+	if s.httpServer == nil {
+		return nil
+	}
+	// 升级后的连接不受Shutdown管理, 这里只关闭监听
+	_ = s.httpServer.Shutdown(ctx)
+	err := s.tracker.Wait(ctx)
+	s.closeConns()
+	_ = s.httpServer.Close()
+	return err
+}
+
+func (s *Engine) isStopped() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stopped
+}
+
+func (s *Engine) closeConns() {
+	s.mu.Lock()
+	s.stopped = true
+	conns := make([]*Conn, 0, len(s.activeConn))
+	for c := range s.activeConn {
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+	deadline := time.Now().Add(time.Second)
+	msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutdown")
+	for _, c := range conns {
+		_ = c.c.WriteControl(websocket.CloseMessage, msg, deadline)
+		_ = c.Close()
+	}
 }
 
-func (s *Engine) trackConn(c *Conn, add bool) {
+// trackConn 记录连接, Stop关闭连接后添加返回false
+func (s *Engine) trackConn(c *Conn, add bool) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if s.activeConn == nil {
 		s.activeConn = make(map[*Conn]struct{})
 	}
-	if add {
+	if add && s.stopped {
+		return false
+	}
+	_, has := s.activeConn[c]
+	if add && !has {
 		s.activeConn[c] = struct{}{}
-	} else {
+		wsConnections.Inc()
+	} else if !add && has {
 		delete(s.activeConn, c)
+		wsConnections.Dec()
+	}
+	return true
+}
+
+// RouteList 实现server.RouteLister, Path为消息id
+func (s *Engine) RouteList() []gServer.Route {
+	s.mu.Lock()
+	ids := make([]int32, 0, len(s.handlers))
+	for id := range s.handlers {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+	routes := make([]gServer.Route, 0, len(ids))
+	for _, id := range ids {
+		var handler string
+		if cs := s.handlers[id]; len(cs) > 0 {
+			handler = gServer.FuncName(cs[len(cs)-1])
+		}
+		routes = append(routes, gServer.Route{Path: strconv.Itoa(int(id)), Handler: handler})
 	}
+	s.mu.Unlock()
+	return routes
 }
 
 func (s *Engine) addCore(id int32, cores ...core) {
@@ -8,6 +8,7 @@ import (
 
 	"github.com/wangshanqi84-gif/sagittarius/cores/logger"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -64,6 +65,22 @@ func MaxLifeTime(maxLifetime time.Duration) Option {
 	}
 }
 
+// Name
+// 设置名称, 用于指标标签
+func Name(name string) Option {
+	return func(c *Client) {
+		c.name = name
+	}
+}
+
+// SlowThreshold
+// 设置慢查询日志阈值 默认500ms, 小于0时不记录慢查询
+func SlowThreshold(slowThreshold time.Duration) Option {
+	return func(c *Client) {
+		c.slowThreshold = slowThreshold
+	}
+}
+
 // DriverName
 // 设置db类型
 func DriverName(driverName string) Option {
@@ -73,21 +90,25 @@ func DriverName(driverName string) Option {
 }
 
 type Client struct {
-	driverName  string
-	resolver    *dbresolver.DBResolver
-	db          *gorm.DB
-	gormCfg     *gorm.Config
-	logger      *logger.Logger
-	maxOpen     int
-	maxIdle     int
-	maxLifetime time.Duration
-	maxIdleTime time.Duration
+	name          string
+	driverName    string
+	resolver      *dbresolver.DBResolver
+	db            *gorm.DB
+	gormCfg       *gorm.Config
+	logger        *logger.Logger
+	stats         prometheus.Collector
+	maxOpen       int
+	maxIdle       int
+	maxLifetime   time.Duration
+	maxIdleTime   time.Duration
+	slowThreshold time.Duration
 }
 
 func NewClient(master string, slave []string, opts ...Option) (*Client, error) {
 	// 初始化
 	c := &Client{
-		driverName: "mysql",
+		driverName:    "mysql",
+		slowThreshold: DefaultSlowThreshold,
 	}
 	// option 执行
 	for _, opt := range opts {
@@ -150,6 +171,9 @@ func NewClient(master string, slave []string, opts ...Option) (*Client, error) {
 	if err = sqlDB.Ping(); err != nil {
 		return nil, errors.New(fmt.Sprintf("database ping failed: %v", err))
 	}
+	if err = c.registerMetrics(); err != nil {
+		return nil, err
+	}
 	return c, nil
 }
 
@@ -168,7 +192,8 @@ func (c *Client) open(master string) error {
 	}
 	if c.logger != nil {
 		c.gormCfg.Logger = &OrmLogger{
-			Logger: c.logger,
+			Logger:        c.logger,
+			SlowThreshold: c.slowThreshold,
 		}
 	}
 	var db *gorm.DB
@@ -211,6 +236,9 @@ func (c *Client) Ping() error {
 }
 
 func (c *Client) Close() error {
+	if c.stats != nil {
+		prometheus.Unregister(c.stats)
+	}
 	db, err := c.db.DB()
 	if err != nil {
 		return err
@@ -11,4 +11,5 @@ const (
 	DefaultPostgresMaxOpen = 25
 	DefaultLifeTime        = time.Hour
 	DefaultIdleTime        = 15 * time.Minute
+	DefaultSlowThreshold   = 500 * time.Millisecond
 )
@@ -13,11 +13,18 @@ import (
 
 type OrmLogger struct {
 	*log.Logger
+	// SlowThreshold 慢查询阈值, 0时使用默认值, 小于0时不记录慢查询
+	SlowThreshold time.Duration
 }
 
 // Trace 实现gorm logger接口用
 func (l *OrmLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
-	elapsed := time.Since(begin).Nanoseconds() / 1e6
+	cost := time.Since(begin)
+	elapsed := cost.Nanoseconds() / 1e6
+	slow := l.SlowThreshold
+	if slow == 0 {
+		slow = DefaultSlowThreshold
+	}
 	switch {
 	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
 		sql, rows := fc()
@@ -26,7 +33,7 @@ func (l *OrmLogger) Trace(ctx context.Context, begin time.Time, fc func() (strin
 		} else {
 			l.Error(ctx, "(GORM_ERR_LOG): err:%v, coast:%d, [%d]sql:%s", err, elapsed, rows, sql)
 		}
-	case elapsed > 500:
+	case slow > 0 && cost > slow:
 		sql, rows := fc()
 		if rows == -1 {
 			l.Warn(ctx, "(GORM_SLOW_LOG): coast:%d, [-]sql:%s", elapsed, sql)
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"gorm.io/gorm"
+)
+
+const (
+	metricsStartKey = "sagittarius:metrics_start"
+)
+
+var (
+	dbQueries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_client_queries_total",
+		Help: "Total number of SQL statements executed by the client.",
+	}, []string{"db", "operation", "table", "status"})
+	dbDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_client_query_duration_seconds",
+		Help:    "Histogram of SQL statement latency (seconds).",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"db", "operation", "table"})
+)
+
+func init() {
+	prometheus.MustRegister(dbQueries, dbDuration)
+}
+
+// registerMetrics 注册gorm回调统计语句耗时, 并导出连接池状态
+func (c *Client) registerMetrics() error {
+	cb := c.db.Callback()
+	errs := []error{
+		cb.Create().Before("gorm:create").Register("metrics:before_create", beforeCallback),
+		cb.Create().After("gorm:create").Register("metrics:after_create", c.afterCallback("create")),
+		cb.Query().Before("gorm:query").Register("metrics:before_query", beforeCallback),
+		cb.Query().After("gorm:query").Register("metrics:after_query", c.afterCallback("query")),
+		cb.Update().Before("gorm:update").Register("metrics:before_update", beforeCallback),
+		cb.Update().After("gorm:update").Register("metrics:after_update", c.afterCallback("update")),
+		cb.Delete().Before("gorm:delete").Register("metrics:before_delete", beforeCallback),
+		cb.Delete().After("gorm:delete").Register("metrics:after_delete", c.afterCallback("delete")),
+		cb.Row().Before("gorm:row").Register("metrics:before_row", beforeCallback),
+		cb.Row().After("gorm:row").Register("metrics:after_row", c.afterCallback("row")),
+		cb.Raw().Before("gorm:raw").Register("metrics:before_raw", beforeCallback),
+		cb.Raw().After("gorm:raw").Register("metrics:after_raw", c.afterCallback("raw")),
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	sqlDB, err := c.db.DB()
+	if err != nil {
+		return err
+	}
+	// 同名client重复创建时沿用已注册的collector
+	stats := collectors.NewDBStatsCollector(sqlDB, c.name)
+	if prometheus.Register(stats) == nil {
+		c.stats = stats
+	}
+	return nil
+}
+
+func beforeCallback(db *gorm.DB) {
+	db.InstanceSet(metricsStartKey, time.Now())
+}
+
+func (c *Client) afterCallback(op string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		v, ok := db.InstanceGet(metricsStartKey)
+		if !ok {
+			return
+		}
+		start, ok := v.(time.Time)
+		if !ok {
+			return
+		}
+		table := db.Statement.Table
+		status := "ok"
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			status = "error"
+		}
+		dbQueries.WithLabelValues(c.name, op, table, status).Inc()
+		dbDuration.WithLabelValues(c.name, op, table).Observe(time.Since(start).Seconds())
+	}
+}
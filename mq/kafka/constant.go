@@ -19,6 +19,11 @@ var (
 	Version = sarama.V3_4_0_0
 )
 
+const (
+	// 默认慢调用阈值
+	defaultSlowThreshold = time.Second
+)
+
 /////////////////////////////////////
 // 消费者常量定义
 /////////////////////////////////////
@@ -7,6 +7,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/wangshanqi84-gif/sagittarius/cores/logger"
 	"github.com/wangshanqi84-gif/sagittarius/mq/kafka/core"
 
 	"github.com/IBM/sarama"
@@ -25,6 +26,8 @@ type consumerOption struct {
 	clientID          string
 	workerNumbers     int
 	seqNumbers        int
+	slowThreshold     time.Duration
+	logger            *logger.Logger
 }
 
 func ConsumerReBalance(reBalance []string) ConsumerOption {
@@ -87,6 +90,20 @@ func ConsumerSeqNumbers(seqNumbers int) ConsumerOption {
 	}
 }
 
+// ConsumerSlowThreshold 慢处理阈值 默认1s, 小于等于0时不记录
+func ConsumerSlowThreshold(slowThreshold time.Duration) ConsumerOption {
+	return func(o *consumerOption) {
+		o.slowThreshold = slowThreshold
+	}
+}
+
+// ConsumerLogger 慢处理日志记录器, 不设置时不记录
+func ConsumerLogger(lgr *logger.Logger) ConsumerOption {
+	return func(o *consumerOption) {
+		o.logger = lgr
+	}
+}
+
 type Consumer struct {
 	gc     *core.GroupConsumer
 	topics map[string]string
@@ -109,6 +126,7 @@ func NewConsumer(ctx context.Context, groupName string, brokers []string, topics
 		topicCreateEnable: false,
 		workerNumbers:     defaultConsumerWorkerNumbers,
 		seqNumbers:        defaultConsumerSeqNumbers,
+		slowThreshold:     defaultSlowThreshold,
 	}
 	for _, opt := range opts {
 		if opt != nil {
@@ -152,7 +170,8 @@ func NewConsumer(ctx context.Context, groupName string, brokers []string, topics
 		topicList = append(topicList, v)
 	}
 	gc, err := core.NewGroupConsumer(ctx, cfg, groupName, brokers, option.topicCreateEnable,
-		option.autoCommit, option.builder, topicList, option.workerNumbers, option.seqNumbers)
+		option.autoCommit, option.builder, topicList, option.workerNumbers, option.seqNumbers,
+		&core.Monitor{SlowThreshold: option.slowThreshold, Logger: option.logger})
 	if err != nil {
 		return nil, err
 	}
@@ -197,6 +216,11 @@ func (c *Consumer) TopicAlias() []string {
 	return alias
 }
 
+// Ping 检查与kafka集群的连接
+func (c *Consumer) Ping(ctx context.Context) error {
+	return c.gc.Ping(ctx)
+}
+
 func (c *Consumer) Start() {
 	c.gc.Start()
 }
@@ -59,11 +59,11 @@ func (b *Builder) ProducerMessage(ctx context.Context, topic string, key []byte,
 		if ok {
 			m.SetUberMeta(fmt.Sprintf("%s.%s.%s", td.Namespace, td.Product, td.ServiceName))
 		}
-		err := b.tracer.Inject(span.Context(), opentracing.TextMap, m)
-		if err != nil {
-			// 注入失败则直接返回消息
-			return &pm
+		if id := gCtx.FromRequestIDContext(ctx); id != "" {
+			m.SetRequestID(id)
 		}
+		// 注入失败时仍传递服务及请求ID信息
+		_ = b.tracer.Inject(span.Context(), opentracing.TextMap, m)
 		// 将注入信息写入header进行传递
 		pm.msg.Headers = append(pm.msg.Headers, m.Data...)
 	}
@@ -95,6 +95,9 @@ func (b *Builder) ConsumerMessage(ctx context.Context, message *sarama.ConsumerM
 				opentracing.Tag{Key: string(ext.Component), Value: "kafka"},
 			}
 		}
+		if id := m.GetRequestID(); gCtx.ValidRequestID(id) {
+			ctx = gCtx.NewRequestIDContext(ctx, id)
+		}
 		sk := m.GetUberMeta()
 		if sk != "" {
 			ss := strings.Split(sk, ".")
@@ -110,6 +113,9 @@ func (b *Builder) ConsumerMessage(ctx context.Context, message *sarama.ConsumerM
 			opentracing.Tag{Key: string(ext.Component), Value: "kafka"},
 		}
 	}
+	if gCtx.FromRequestIDContext(ctx) == "" {
+		ctx = gCtx.NewRequestIDContext(ctx, gCtx.NewRequestID())
+	}
 	span := b.tracer.StartSpan(message.Topic, opts...)
 	c := opentracing.ContextWithSpan(ctx, span)
 	return &ConsumerMessage{
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/IBM/sarama"
 	"github.com/pkg/errors"
@@ -14,14 +15,17 @@ type Handler func(ctx context.Context, msg *ConsumerMessage)
 
 type GroupConsumer struct {
 	ctx           context.Context
+	groupName     string
 	topics        []string
 	autoCommit    bool
 	builder       IMessageBuilder
+	client        sarama.Client
 	group         sarama.ConsumerGroup
 	kafkaVer      sarama.KafkaVersion
 	handlers      map[string][]Handler
 	workerNumbers int
 	workerCh      chan *ConsumerMessage
+	monitor       *Monitor
 }
 
 func (gc *GroupConsumer) Setup(sess sarama.ConsumerGroupSession) error {
@@ -33,12 +37,14 @@ func (gc *GroupConsumer) Cleanup(sess sarama.ConsumerGroupSession) error {
 }
 
 func (gc *GroupConsumer) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	defer resetLag(gc.groupName, claim)
 	for {
 		select {
 		case msg, ok := <-claim.Messages():
 			if !ok {
 				return nil
 			}
+			observeLag(gc.groupName, claim, msg)
 			cm := gc.builder.ConsumerMessage(gc.ctx, msg, gc.kafkaVer)
 			// 这里阻塞写入chan 因此为了效率 消费者应该异步处理
 			cm.sess = sess
@@ -113,7 +119,8 @@ func NewGroupConsumer(
 	builder IMessageBuilder,
 	topics []string,
 	workerNumbers int,
-	seqNumbers int) (*GroupConsumer, error) {
+	seqNumbers int,
+	monitor *Monitor) (*GroupConsumer, error) {
 	// 初始化client
 	c, err := sarama.NewClient(brokers, cfg)
 	if err != nil {
@@ -152,13 +159,16 @@ func NewGroupConsumer(
 	}
 	gc := GroupConsumer{
 		ctx:           ctx,
+		groupName:     groupName,
 		topics:        topics,
 		autoCommit:    autoCommit,
 		builder:       builder,
+		client:        c,
 		group:         group,
 		kafkaVer:      cfg.Version,
 		workerNumbers: workerNumbers,
 		handlers:      make(map[string][]Handler),
+		monitor:       monitor,
 	}
 	if seqNumbers > 0 {
 		gc.workerCh = make(chan *ConsumerMessage, seqNumbers)
@@ -175,9 +185,11 @@ func NewGroupConsumer(
 						return
 					}
 					if _, has := gc.handlers[msg.Topic()]; has {
+						start := time.Now()
 						for _, hd := range gc.handlers[msg.Topic()] {
 							hd(msg.Ctx(), msg)
 						}
+						gc.monitor.observeHandle(msg.Ctx(), gc.groupName, msg, start)
 					}
 					if gc.autoCommit {
 						// 标记
@@ -193,6 +205,10 @@ func NewGroupConsumer(
 	return &gc, nil
 }
 
+func (gc *GroupConsumer) Ping(ctx context.Context) error {
+	return ping(ctx, gc.client)
+}
+
 func (gc *GroupConsumer) Start() {
 	gc.start(gc.topics, gc)
 }
@@ -1,6 +1,8 @@
 package core
 
 import (
+	gCtx "github.com/wangshanqi84-gif/sagittarius/cores/context"
+
 	"github.com/IBM/sarama"
 )
 
@@ -28,6 +30,22 @@ func (tm *TextMapMeta) GetUberMeta() string {
 	return ""
 }
 
+func (tm *TextMapMeta) SetRequestID(id string) {
+	tm.Data = append(tm.Data, sarama.RecordHeader{
+		Key:   []byte(gCtx.UberRequestIDKey()),
+		Value: []byte(id),
+	})
+}
+
+func (tm *TextMapMeta) GetRequestID() string {
+	for _, h := range tm.Data {
+		if string(h.Key) == gCtx.UberRequestIDKey() {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
 func (tm *TextMapMeta) Set(key, val string) {
 	tm.Data = append(tm.Data, sarama.RecordHeader{
 		Key:   []byte(key),
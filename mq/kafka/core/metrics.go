@@ -0,0 +1,115 @@
+package core
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/wangshanqi84-gif/sagittarius/cores/logger"
+
+	"github.com/IBM/sarama"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	statusOK    = "ok"
+	statusError = "error"
+)
+
+var (
+	producerMessages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_producer_messages_total",
+		Help: "Total number of messages produced to Kafka.",
+	}, []string{"name", "topic", "status"})
+	producerDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kafka_producer_send_duration_seconds",
+		Help:    "Histogram of Kafka produce latency (seconds) until acknowledged.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"name", "topic"})
+	consumerMessages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_consumer_messages_total",
+		Help: "Total number of Kafka messages handled by the consumer.",
+	}, []string{"group", "topic"})
+	consumerDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kafka_consumer_handle_duration_seconds",
+		Help:    "Histogram of Kafka message handler latency (seconds).",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"group", "topic"})
+	consumerLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kafka_consumer_lag",
+		Help: "Number of messages between the high water mark and the last received offset per partition.",
+	}, []string{"group", "topic", "partition"})
+)
+
+func init() {
+	prometheus.MustRegister(producerMessages, producerDuration, consumerMessages, consumerDuration, consumerLag)
+}
+
+// Monitor 指标标签及慢调用日志配置
+type Monitor struct {
+	// Name 生产者指标标签, 消费者使用group
+	Name string
+	// SlowThreshold 慢调用阈值, 小于等于0时不记录
+	SlowThreshold time.Duration
+	// Logger 慢调用日志记录器, nil时不记录
+	Logger *logger.Logger
+}
+
+func (m *Monitor) name() string {
+	if m == nil {
+		return ""
+	}
+	return m.Name
+}
+
+func (m *Monitor) slow(ctx context.Context, cost time.Duration, format string, args ...interface{}) {
+	if m == nil || m.Logger == nil || m.SlowThreshold <= 0 || cost <= m.SlowThreshold {
+		return
+	}
+	m.Logger.Warn(ctx, format, append([]interface{}{cost.Milliseconds()}, args...)...)
+}
+
+// observeProduce 记录发送结果, start为发送时间
+func (m *Monitor) observeProduce(ctx context.Context, topic string, start time.Time, n int, err error) {
+	cost := time.Since(start)
+	status := statusOK
+	if err != nil {
+		status = statusError
+	}
+	producerMessages.WithLabelValues(m.name(), topic, status).Add(float64(n))
+	producerDuration.WithLabelValues(m.name(), topic).Observe(cost.Seconds())
+	m.slow(ctx, cost, "(KAFKA_SLOW_LOG): coast:%d, produce name:%s, topic:%s, count:%d", m.name(), topic, n)
+}
+
+// observeAsync 异步发送结果, 发送时间由生产者按消息记录, 不占用调用方的Metadata
+func (m *Monitor) observeAsync(msg *sarama.ProducerMessage, sent *sync.Map, err error) {
+	v, ok := sent.LoadAndDelete(msg)
+	if !ok {
+		return
+	}
+	m.observeProduce(context.TODO(), msg.Topic, v.(time.Time), 1, err)
+}
+
+// observeLag 记录分区积压
+func observeLag(group string, claim sarama.ConsumerGroupClaim, msg *sarama.ConsumerMessage) {
+	lag := claim.HighWaterMarkOffset() - msg.Offset - 1
+	if lag < 0 {
+		lag = 0
+	}
+	consumerLag.WithLabelValues(group, msg.Topic, strconv.Itoa(int(msg.Partition))).Set(float64(lag))
+}
+
+// resetLag 分区被回收时删除积压指标
+func resetLag(group string, claim sarama.ConsumerGroupClaim) {
+	consumerLag.DeleteLabelValues(group, claim.Topic(), strconv.Itoa(int(claim.Partition())))
+}
+
+// observeHandle 记录消息处理耗时
+func (m *Monitor) observeHandle(ctx context.Context, group string, msg *ConsumerMessage, start time.Time) {
+	cost := time.Since(start)
+	consumerMessages.WithLabelValues(group, msg.Topic()).Inc()
+	consumerDuration.WithLabelValues(group, msg.Topic()).Observe(cost.Seconds())
+	m.slow(ctx, cost, "(KAFKA_SLOW_LOG): coast:%d, consume group:%s, topic:%s, partition:%d, offset:%d",
+		group, msg.Topic(), msg.msg.Partition, msg.msg.Offset)
+}
@@ -0,0 +1,45 @@
+package core
+
+import (
+	"context"
+
+	"github.com/IBM/sarama"
+	"github.com/pkg/errors"
+)
+
+// ping 检查与kafka集群的连接, 任一broker可连接即认为正常
+func ping(ctx context.Context, client sarama.Client) error {
+	if client.Closed() {
+		return sarama.ErrClosedClient
+	}
+	done := make(chan error, 1)
+	go func() {
+		var err error = sarama.ErrOutOfBrokers
+		for _, b := range client.Brokers() {
+			if ok, _ := b.Connected(); ok {
+				done <- nil
+				return
+			}
+			// Open异步建立连接, Connected等待连接结果
+			if e := b.Open(client.Config()); e != nil && !errors.Is(e, sarama.ErrAlreadyConnected) {
+				err = e
+				continue
+			}
+			ok, e := b.Connected()
+			if ok {
+				done <- nil
+				return
+			}
+			if e != nil {
+				err = e
+			}
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -2,6 +2,8 @@ package core
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/IBM/sarama"
 	"github.com/pkg/errors"
@@ -27,6 +29,7 @@ type IProducer interface {
 	SendMessages(ctx context.Context, alias string, md []*MessageData)
 	Success() chan *sarama.ProducerMessage
 	Error() chan *sarama.ProducerError
+	Ping(ctx context.Context) error
 }
 
 // 同步生产者相关
@@ -35,23 +38,32 @@ type SyncProducer struct {
 	ctx      context.Context
 	builder  IMessageBuilder
 	topics   map[string]string
+	client   sarama.Client
 	sp       sarama.SyncProducer
 	kafkaVer sarama.KafkaVersion
+	monitor  *Monitor
 	errChan  chan *sarama.ProducerError
 	succChan chan *sarama.ProducerMessage
 }
 
 func NewSyncProducer(ctx context.Context, brokers []string, builder IMessageBuilder,
-	topics map[string]string, cfg *sarama.Config) (*SyncProducer, error) {
+	topics map[string]string, cfg *sarama.Config, monitor *Monitor) (*SyncProducer, error) {
 	// 初始化同步生产者
-	p, err := sarama.NewSyncProducer(brokers, cfg)
+	c, err := sarama.NewClient(brokers, cfg)
 	if err != nil {
 		return nil, errors.Wrap(err, "new sync producer")
 	}
+	p, err := sarama.NewSyncProducerFromClient(c)
+	if err != nil {
+		_ = c.Close()
+		return nil, errors.Wrap(err, "new sync producer")
+	}
 	sp := SyncProducer{
 		ctx:      ctx,
+		client:   c,
 		sp:       p,
 		kafkaVer: cfg.Version,
+		monitor:  monitor,
 		builder:  builder,
 		topics:   make(map[string]string),
 		errChan:  make(chan *sarama.ProducerError, 1),
@@ -64,6 +76,7 @@ func NewSyncProducer(ctx context.Context, brokers []string, builder IMessageBuil
 		select {
 		case <-producer.ctx.Done():
 			producer.sp.Close()
+			producer.client.Close()
 		}
 	}(&sp)
 	return &sp, nil
@@ -76,7 +89,9 @@ func (sp *SyncProducer) SendMessage(ctx context.Context, alias string, key []byt
 		topic = sp.topics[alias]
 	}
 	msg := sp.builder.ProducerMessage(ctx, topic, key, data, sp.kafkaVer)
+	start := time.Now()
 	_, _, err := sp.sp.SendMessage(msg.msg)
+	sp.monitor.observeProduce(ctx, topic, start, 1, err)
 	if err != nil {
 		sp.errChan <- &sarama.ProducerError{Msg: msg.msg, Err: err}
 	} else {
@@ -95,7 +110,10 @@ func (sp *SyncProducer) SendMessages(ctx context.Context, alias string, md []*Me
 		msg := sp.builder.ProducerMessage(ctx, topic, data.Key, data.Data, sp.kafkaVer)
 		msgs = append(msgs, msg.msg)
 	}
-	if err := sp.sp.SendMessages(msgs); err != nil {
+	start := time.Now()
+	err := sp.sp.SendMessages(msgs)
+	sp.monitor.observeProduce(ctx, topic, start, len(msgs), err)
+	if err != nil {
 		sp.errChan <- &sarama.ProducerError{Msg: msgs[0], Err: err}
 	} else {
 		sp.succChan <- msgs[0]
@@ -110,30 +128,45 @@ func (sp *SyncProducer) Error() chan *sarama.ProducerError {
 	return sp.errChan
 }
 
+func (sp *SyncProducer) Ping(ctx context.Context) error {
+	return ping(ctx, sp.client)
+}
+
 // 异步生产者
 
 type AsyncProducer struct {
 	ctx      context.Context
 	builder  IMessageBuilder
 	topics   map[string]string
+	client   sarama.Client
 	ap       sarama.AsyncProducer
 	kafkaVer sarama.KafkaVersion
+	monitor  *Monitor
 	errChan  chan *sarama.ProducerError
 	succChan chan *sarama.ProducerMessage
+	// 消息发送时间, key为*sarama.ProducerMessage, 结果返回时统计耗时
+	sent sync.Map
 }
 
 func NewAsyncProducer(ctx context.Context, brokers []string, builder IMessageBuilder,
-	topics map[string]string, cfg *sarama.Config) (*AsyncProducer, error) {
+	topics map[string]string, cfg *sarama.Config, monitor *Monitor) (*AsyncProducer, error) {
 	// 初始化异步生产者
-	p, err := sarama.NewAsyncProducer(brokers, cfg)
+	c, err := sarama.NewClient(brokers, cfg)
 	if err != nil {
 		return nil, errors.Wrap(err, "new async producer")
 	}
+	p, err := sarama.NewAsyncProducerFromClient(c)
+	if err != nil {
+		_ = c.Close()
+		return nil, errors.Wrap(err, "new async producer")
+	}
 	ap := AsyncProducer{
 		ctx:      ctx,
 		builder:  builder,
+		client:   c,
 		ap:       p,
 		kafkaVer: cfg.Version,
+		monitor:  monitor,
 		topics:   make(map[string]string),
 		errChan:  make(chan *sarama.ProducerError, _asyncChanSize),
 		succChan: make(chan *sarama.ProducerMessage, _asyncChanSize),
@@ -146,11 +179,14 @@ func NewAsyncProducer(ctx context.Context, brokers []string, builder IMessageBui
 		for {
 			select {
 			case e := <-producer.ap.Errors():
+				producer.monitor.observeAsync(e.Msg, &producer.sent, e.Err)
 				producer.errChan <- e
 			case msg := <-producer.ap.Successes():
+				producer.monitor.observeAsync(msg, &producer.sent, nil)
 				producer.succChan <- msg
 			case <-producer.ctx.Done():
 				producer.ap.Close()
+				producer.client.Close()
 				return
 			}
 		}
@@ -165,6 +201,8 @@ func (ap *AsyncProducer) SendMessage(ctx context.Context, alias string, key []by
 		topic = ap.topics[alias]
 	}
 	msg := ap.builder.ProducerMessage(ctx, topic, key, data, ap.kafkaVer)
+	// 记录发送时间, 结果返回时统计耗时
+	ap.sent.Store(msg.msg, time.Now())
 	ap.ap.Input() <- msg.msg
 }
 
@@ -180,6 +218,7 @@ func (ap *AsyncProducer) SendMessages(ctx context.Context, alias string, md []*M
 		msgs = append(msgs, msg.msg)
 	}
 	for _, msg := range msgs {
+		ap.sent.Store(msg, time.Now())
 		ap.ap.Input() <- msg
 	}
 }
@@ -191,3 +230,7 @@ func (ap *AsyncProducer) Success() chan *sarama.ProducerMessage {
 func (ap *AsyncProducer) Error() chan *sarama.ProducerError {
 	return ap.errChan
 }
+
+func (ap *AsyncProducer) Ping(ctx context.Context) error {
+	return ping(ctx, ap.client)
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/wangshanqi84-gif/sagittarius/cores/logger"
 	"github.com/wangshanqi84-gif/sagittarius/mq/kafka/core"
 
 	"github.com/IBM/sarama"
@@ -27,6 +28,9 @@ type producerOption struct {
 	clientID        string
 	topics          map[string]string // K-别名 V-topic名
 	notifyDisable   bool
+	name            string
+	slowThreshold   time.Duration
+	logger          *logger.Logger
 }
 
 func ProducerTimeout(timeout time.Duration) ProducerOption {
@@ -77,6 +81,27 @@ func ProducerClientID(clientID string) ProducerOption {
 	}
 }
 
+// ProducerName 名称, 用于指标标签
+func ProducerName(name string) ProducerOption {
+	return func(o *producerOption) {
+		o.name = name
+	}
+}
+
+// ProducerSlowThreshold 慢发送阈值 默认1s, 小于等于0时不记录
+func ProducerSlowThreshold(slowThreshold time.Duration) ProducerOption {
+	return func(o *producerOption) {
+		o.slowThreshold = slowThreshold
+	}
+}
+
+// ProducerLogger 慢发送日志记录器, 不设置时不记录
+func ProducerLogger(lgr *logger.Logger) ProducerOption {
+	return func(o *producerOption) {
+		o.logger = lgr
+	}
+}
+
 func Topics(topics map[string]string) ProducerOption {
 	return func(o *producerOption) {
 		o.topics = make(map[string]string)
@@ -110,6 +135,7 @@ func NewProducer(ctx context.Context, brokers []string, opts ...ProducerOption)
 	option := producerOption{
 		retry:           defaultProducerRetryTimes,
 		maxMessageBytes: defaultProducerMaxMessageBytes,
+		slowThreshold:   defaultSlowThreshold,
 	}
 	for _, opt := range opts {
 		if opt != nil {
@@ -134,13 +160,18 @@ func NewProducer(ctx context.Context, brokers []string, opts ...ProducerOption)
 	if len(option.clientID) > 0 {
 		cfg.ClientID = option.clientID
 	}
+	monitor := &core.Monitor{
+		Name:          option.name,
+		SlowThreshold: option.slowThreshold,
+		Logger:        option.logger,
+	}
 	var producer core.IProducer
 	var err error
 	switch option.model {
 	case "async":
-		producer, err = core.NewAsyncProducer(ctx, brokers, option.builder, option.topics, cfg)
+		producer, err = core.NewAsyncProducer(ctx, brokers, option.builder, option.topics, cfg, monitor)
 	default:
-		producer, err = core.NewSyncProducer(ctx, brokers, option.builder, option.topics, cfg)
+		producer, err = core.NewSyncProducer(ctx, brokers, option.builder, option.topics, cfg, monitor)
 	}
 	if err != nil {
 		return nil, err
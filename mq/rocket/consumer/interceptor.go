@@ -31,3 +31,29 @@ func LogInterceptor(lgr *logger.Logger) primitive.Interceptor {
 		return err
 	}
 }
+
+// MetricsInterceptor 统计消费耗时及结果, 以消费组及topic为标签, 超过slow时记录慢消费日志
+func MetricsInterceptor(slow time.Duration, lgr *logger.Logger) primitive.Interceptor {
+	return func(ctx context.Context, req, reply interface{}, next primitive.Invoker) error {
+		begin := time.Now()
+		err := next(ctx, req, reply)
+		cost := time.Since(begin)
+		msgs, ok := req.([]*primitive.MessageExt)
+		if !ok || len(msgs) == 0 {
+			return err
+		}
+		group := ""
+		if mc, has := primitive.GetConsumerCtx(ctx); has {
+			group = mc.ConsumerGroup
+		}
+		topic := msgs[0].Topic
+		result := consumeResult(reply, err)
+		consumeMessages.WithLabelValues(group, topic, result).Add(float64(len(msgs)))
+		consumeDuration.WithLabelValues(group, topic).Observe(cost.Seconds())
+		if lgr != nil && slow > 0 && cost > slow {
+			lgr.Warn(ctx, "(ROCKET_SLOW_LOG): coast:%d, consume group:%s, topic:%s, count:%d, result:%s",
+				cost.Milliseconds(), group, topic, len(msgs), result)
+		}
+		return err
+	}
+}
@@ -0,0 +1,45 @@
+package consumer
+
+import (
+	"github.com/apache/rocketmq-client-go/v2/consumer"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	consumeMessages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "rocket_consumer_messages_total",
+		Help: "Total number of RocketMQ messages consumed by result.",
+	}, []string{"group", "topic", "result"})
+	consumeDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "rocket_consumer_consume_duration_seconds",
+		Help:    "Histogram of RocketMQ consume latency (seconds) per batch.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"group", "topic"})
+
+	consumeResults = map[consumer.ConsumeResult]string{
+		consumer.ConsumeSuccess:             "success",
+		consumer.ConsumeRetryLater:          "retry_later",
+		consumer.Commit:                     "commit",
+		consumer.Rollback:                   "rollback",
+		consumer.SuspendCurrentQueueAMoment: "suspend",
+	}
+)
+
+func init() {
+	prometheus.MustRegister(consumeMessages, consumeDuration)
+}
+
+// consumeResult 消费结果标签
+func consumeResult(reply interface{}, err error) string {
+	if err != nil {
+		return "error"
+	}
+	holder, ok := reply.(*consumer.ConsumeResultHolder)
+	if !ok || holder == nil {
+		return "unknown"
+	}
+	if s, has := consumeResults[holder.ConsumeResult]; has {
+		return s
+	}
+	return "unknown"
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	gCtx "github.com/wangshanqi84-gif/sagittarius/cores/context"
 	"github.com/wangshanqi84-gif/sagittarius/mq/rocket/metadata"
 
 	"github.com/apache/rocketmq-client-go/v2"
@@ -105,6 +106,15 @@ func (p *PushConsumer) RegisterHandler(topic string, f OnMessage) error {
 				defer span.Finish()
 			}
 		}
+		// 批量消息使用第一条消息的请求ID
+		id := ""
+		if len(msgs) > 0 {
+			id = msgs[0].GetProperty(gCtx.UberRequestIDKey())
+		}
+		if !gCtx.ValidRequestID(id) {
+			id = gCtx.NewRequestID()
+		}
+		ctx = gCtx.NewRequestIDContext(ctx, id)
 		err := f(ctx, msgs...)
 		if err != nil {
 			return consumer.ConsumeRetryLater, err
@@ -28,3 +28,23 @@ func LogInterceptor(lgr *logger.Logger) primitive.Interceptor {
 		return err
 	}
 }
+
+// MetricsInterceptor 统计发送耗时及结果, name为指标标签, 超过slow时记录慢发送日志
+func MetricsInterceptor(name string, slow time.Duration, lgr *logger.Logger) primitive.Interceptor {
+	return func(ctx context.Context, req, reply interface{}, next primitive.Invoker) error {
+		begin := time.Now()
+		err := next(ctx, req, reply)
+		cost := time.Since(begin)
+		msg, ok := req.(*primitive.Message)
+		if !ok {
+			return err
+		}
+		result := sendResult(reply, err)
+		sendMessages.WithLabelValues(name, msg.Topic, result).Inc()
+		sendDuration.WithLabelValues(name, msg.Topic).Observe(cost.Seconds())
+		if lgr != nil && slow > 0 && cost > slow {
+			lgr.Warn(ctx, "(ROCKET_SLOW_LOG): coast:%d, send name:%s, topic:%s, result:%s", cost.Milliseconds(), name, msg.Topic, result)
+		}
+		return err
+	}
+}
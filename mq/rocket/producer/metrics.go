@@ -0,0 +1,45 @@
+package producer
+
+import (
+	"github.com/apache/rocketmq-client-go/v2/primitive"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	sendMessages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "rocket_producer_messages_total",
+		Help: "Total number of messages sent to RocketMQ by result.",
+	}, []string{"name", "topic", "result"})
+	sendDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "rocket_producer_send_duration_seconds",
+		Help:    "Histogram of RocketMQ send latency (seconds).",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"name", "topic"})
+
+	sendStatus = map[primitive.SendStatus]string{
+		primitive.SendOK:                "ok",
+		primitive.SendFlushDiskTimeout:  "flush_disk_timeout",
+		primitive.SendFlushSlaveTimeout: "flush_slave_timeout",
+		primitive.SendSlaveNotAvailable: "slave_not_available",
+		primitive.SendUnknownError:      "unknown_error",
+	}
+)
+
+func init() {
+	prometheus.MustRegister(sendMessages, sendDuration)
+}
+
+// sendResult 发送结果标签
+func sendResult(reply interface{}, err error) string {
+	if err != nil {
+		return "error"
+	}
+	result, ok := reply.(*primitive.SendResult)
+	if !ok || result == nil {
+		return "unknown_error"
+	}
+	if s, has := sendStatus[result.Status]; has {
+		return s
+	}
+	return "unknown_error"
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	gCtx "github.com/wangshanqi84-gif/sagittarius/cores/context"
 	"github.com/wangshanqi84-gif/sagittarius/mq/rocket/metadata"
 
 	"github.com/apache/rocketmq-client-go/v2"
@@ -96,6 +97,10 @@ func (p *Producer) SyncSend(ctx context.Context, alias string,
 	if o.tags != "" {
 		msg = msg.WithTag(o.tags)
 	}
+	// 传递请求ID
+	if id := gCtx.FromRequestIDContext(ctx); id != "" {
+		msg.WithProperty(gCtx.UberRequestIDKey(), id)
+	}
 	// 链路追踪
 	if p.tracer != nil {
 		// 从context中获取spanContext,如果上层没有开启追踪，则这里新建一个
@@ -37,6 +37,7 @@ func buildSentinel(c *Client) {
 	// 创建redsync实例
 	c.rs = redsync.New(goredis.NewPool(cmd))
 	c.IRedisCmd = cmd
+	c.instrument(cmd)
 }
 
 func buildCluster(c *Client) {
@@ -55,6 +56,7 @@ func buildCluster(c *Client) {
 	// 创建redsync实例
 	c.rs = redsync.New(goredis.NewPool(cmd))
 	c.IRedisCmd = cmd
+	c.instrument(cmd)
 }
 
 func buildSingleton(c *Client) {
@@ -74,4 +76,5 @@ func buildSingleton(c *Client) {
 	// 创建redsync实例
 	c.rs = redsync.New(goredis.NewPool(cmd))
 	c.IRedisCmd = cmd
+	c.instrument(cmd)
 }
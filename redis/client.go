@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/wangshanqi84-gif/sagittarius/cores/logger"
+
 	redisgo "github.com/go-redis/redis/v8"
 	"github.com/go-redsync/redsync/v4"
 	"github.com/pkg/errors"
@@ -108,6 +110,22 @@ func Password(password string) Option {
 	}
 }
 
+// Logger
+// 慢命令日志记录器, 不设置时不记录
+func Logger(lgr *logger.Logger) Option {
+	return func(c *Client) {
+		c.logger = lgr
+	}
+}
+
+// SlowThreshold
+// 慢命令阈值 默认100ms, 小于等于0时不记录
+func SlowThreshold(slowThreshold time.Duration) Option {
+	return func(c *Client) {
+		c.slowThreshold = slowThreshold
+	}
+}
+
 type IRedisCmd interface {
 	redisgo.Cmdable
 	Subscribe(ctx context.Context, channels ...string) *redisgo.PubSub
@@ -178,6 +196,9 @@ type Client struct {
 	minIdleConn  int
 	username     string
 	password     string
+
+	logger        *logger.Logger
+	slowThreshold time.Duration
 }
 
 func NewClient(opts ...Option) (*Client, error) {
@@ -189,6 +210,8 @@ func NewClient(opts ...Option) (*Client, error) {
 		writeTimeout: defaultWriteTimeout,
 		poolSize:     defaultPoolSize,
 		minIdleConn:  defaultMinIdleConn,
+
+		slowThreshold: defaultSlowThreshold,
 	}
 	for _, opt := range opts {
 		if opt != nil {
@@ -1,5 +1,7 @@
 package redis
 
+import "time"
+
 const (
 	defaultDialTimeout  = 5   // default: 5s
 	defaultReadTimeout  = 3   // default: 3s
@@ -8,6 +10,8 @@ const (
 	defaultRetry        = 3   // 默认重试次数
 	defaultPoolSize     = 100 // 默认连接池大小
 	defaultMinIdleConn  = 35  // 默认最小连接数辆
+
+	defaultSlowThreshold = 100 * time.Millisecond // 默认慢命令阈值
 )
 
 const (
@@ -0,0 +1,171 @@
+package redis
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wangshanqi84-gif/sagittarius/cores/logger"
+
+	redisgo "github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	pipelineCommand = "pipeline"
+	// slowArgsMax 慢日志最多记录的参数个数
+	slowArgsMax = 2
+)
+
+type startKey struct{}
+
+var (
+	redisCommands = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "redis_client_commands_total",
+		Help: "Total number of Redis commands executed by the client.",
+	}, []string{"name", "command", "status"})
+	redisDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "redis_client_command_duration_seconds",
+		Help:    "Histogram of Redis command latency (seconds).",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"name", "command"})
+
+	pools = &poolCollector{
+		hits:     prometheus.NewDesc("redis_client_pool_hits_total", "Number of times a free connection was found in the pool.", []string{"name"}, nil),
+		misses:   prometheus.NewDesc("redis_client_pool_misses_total", "Number of times a free connection was NOT found in the pool.", []string{"name"}, nil),
+		timeouts: prometheus.NewDesc("redis_client_pool_timeouts_total", "Number of times a wait timeout occurred.", []string{"name"}, nil),
+		total:    prometheus.NewDesc("redis_client_pool_connections", "Number of total connections in the pool.", []string{"name"}, nil),
+		idle:     prometheus.NewDesc("redis_client_pool_idle_connections", "Number of idle connections in the pool.", []string{"name"}, nil),
+		stale:    prometheus.NewDesc("redis_client_pool_stale_connections_total", "Number of stale connections removed from the pool.", []string{"name"}, nil),
+	}
+)
+
+func init() {
+	prometheus.MustRegister(redisCommands, redisDuration, pools)
+}
+
+// hook 统计命令耗时及记录慢命令
+type hook struct {
+	name string
+	slow time.Duration
+	lgr  *logger.Logger
+}
+
+func (h *hook) BeforeProcess(ctx context.Context, cmd redisgo.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, startKey{}, time.Now()), nil
+}
+
+func (h *hook) AfterProcess(ctx context.Context, cmd redisgo.Cmder) error {
+	h.observe(ctx, cmd.Name(), cmd.Err(), func() string {
+		return formatArgs(cmd)
+	})
+	return nil
+}
+
+func (h *hook) BeforeProcessPipeline(ctx context.Context, cmds []redisgo.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, startKey{}, time.Now()), nil
+}
+
+func (h *hook) AfterProcessPipeline(ctx context.Context, cmds []redisgo.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if e := cmd.Err(); e != nil && e != redisgo.Nil {
+			err = e
+			break
+		}
+	}
+	h.observe(ctx, pipelineCommand, err, func() string {
+		names := make([]string, 0, len(cmds))
+		for _, cmd := range cmds {
+			names = append(names, formatArgs(cmd))
+		}
+		return strings.Join(names, "; ")
+	})
+	return nil
+}
+
+func (h *hook) observe(ctx context.Context, command string, err error, args func() string) {
+	start, ok := ctx.Value(startKey{}).(time.Time)
+	if !ok {
+		return
+	}
+	cost := time.Since(start)
+	status := "ok"
+	if err != nil && err != redisgo.Nil {
+		status = "error"
+	}
+	command = strings.ToLower(command)
+	redisCommands.WithLabelValues(h.name, command, status).Inc()
+	redisDuration.WithLabelValues(h.name, command).Observe(cost.Seconds())
+	if h.lgr != nil && h.slow > 0 && cost > h.slow {
+		h.lgr.Warn(ctx, "(REDIS_SLOW_LOG): name:%s, coast:%d, cmd:%s", h.name, cost.Milliseconds(), args())
+	}
+}
+
+// formatArgs 命令及key, 不记录value
+func formatArgs(cmd redisgo.Cmder) string {
+	args := cmd.Args()
+	if len(args) > slowArgsMax {
+		args = args[:slowArgsMax]
+	}
+	ss := make([]string, 0, len(args))
+	for _, arg := range args {
+		if s, ok := arg.(string); ok {
+			ss = append(ss, s)
+		}
+	}
+	return strings.Join(ss, " ")
+}
+
+type poolStater interface {
+	PoolStats() *redisgo.PoolStats
+}
+
+// poolCollector 导出各redis client连接池状态, 以name区分
+type poolCollector struct {
+	clients sync.Map
+
+	hits     *prometheus.Desc
+	misses   *prometheus.Desc
+	timeouts *prometheus.Desc
+	total    *prometheus.Desc
+	idle     *prometheus.Desc
+	stale    *prometheus.Desc
+}
+
+func (p *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- p.hits
+	ch <- p.misses
+	ch <- p.timeouts
+	ch <- p.total
+	ch <- p.idle
+	ch <- p.stale
+}
+
+func (p *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	p.clients.Range(func(key, value interface{}) bool {
+		name := key.(string)
+		stats := value.(poolStater).PoolStats()
+		ch <- prometheus.MustNewConstMetric(p.hits, prometheus.CounterValue, float64(stats.Hits), name)
+		ch <- prometheus.MustNewConstMetric(p.misses, prometheus.CounterValue, float64(stats.Misses), name)
+		ch <- prometheus.MustNewConstMetric(p.timeouts, prometheus.CounterValue, float64(stats.Timeouts), name)
+		ch <- prometheus.MustNewConstMetric(p.total, prometheus.GaugeValue, float64(stats.TotalConns), name)
+		ch <- prometheus.MustNewConstMetric(p.idle, prometheus.GaugeValue, float64(stats.IdleConns), name)
+		ch <- prometheus.MustNewConstMetric(p.stale, prometheus.CounterValue, float64(stats.StaleConns), name)
+		return true
+	})
+}
+
+// instrument 添加命令hook及连接池统计
+func (c *Client) instrument(cmd interface {
+	poolStater
+	AddHook(redisgo.Hook)
+}) {
+	cmd.AddHook(&hook{
+		name: c.name,
+		slow: c.slowThreshold,
+		lgr:  c.logger,
+	})
+	pools.clients.Store(c.name, cmd)
+}